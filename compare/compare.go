@@ -14,9 +14,77 @@ package compare
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// timestampLayouts are the ISO-8601 forms Timestamp understands. Layouts
+// without an offset are read as UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Values compares two values by their type. When either of them is a
+// number both are compared as float64, reading numbers written as strings,
+// so the result does not depend on which operand comes first. Any other
+// pair, or a number and a string that is not a number, is compared by
+// Compare.
+func Values(a, b any) int {
+	if IsNumber(a) || IsNumber(b) {
+		x, okA := Float(a)
+		y, okB := Float(b)
+		if okA && okB {
+			switch {
+			case x < y:
+				{
+					return -1
+				}
+			case x > y:
+				{
+					return 1
+				}
+			default:
+				{
+					return 0
+				}
+			}
+		}
+	}
+	return Compare(a, b)
+}
+
+// IsNumber reports whether a value is of a numeric type
+func IsNumber(v any) bool {
+	switch v.(type) {
+	case int, int32, int64, int16, int8, uint, uint32, uint64, uint16, byte, float32, float64:
+		{
+			return true
+		}
+	}
+	return false
+}
+
+// Float reads a value of a numeric type, or a string holding a number, as
+// a float64
+func Float(v any) (float64, bool) {
+	if IsNumber(v) {
+		return As[float64](v), true
+	}
+	str, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
 func Compare(a, b any) int {
 	switch t := a.(type) {
 	case int:
@@ -67,6 +135,13 @@ func Compare(a, b any) int {
 		{
 			return compare(t, b)
 		}
+	case time.Time:
+		{
+			if v, ok := Timestamp(b); ok {
+				return t.Compare(v)
+			}
+			return strings.Compare(t.Format(time.RFC3339Nano), fmt.Sprintf("%v", b))
+		}
 	default:
 		{
 			return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
@@ -152,3 +227,22 @@ func compare[T int | int32 | int64 | int16 | int8 | uint | uint32 | uint64 | uin
 	}
 	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", v))
 }
+
+// Timestamp reads an ISO-8601 timestamp from a time.Time or a string
+func Timestamp(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		{
+			return t, true
+		}
+	case string:
+		{
+			for _, layout := range timestampLayouts {
+				if value, err := time.Parse(layout, t); err == nil {
+					return value, true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import "sync"
+
+type (
+	// singletons keeps the results of ONCE and GLOBAL functions and of
+	// aggregates computed over all rows for the duration of one execution
+	singletons struct {
+		mut    sync.Mutex
+		values map[string]*singleton
+	}
+	singleton struct {
+		mut   sync.Mutex
+		done  bool
+		value any
+	}
+)
+
+func newSingletons() *singletons {
+	return &singletons{
+		values: make(map[string]*singleton),
+	}
+}
+
+// Do returns the value stored under name, calling fn to produce it the
+// first time. Failed calls are not stored so the next caller retries.
+func (singletons *singletons) Do(name string, fn func() (any, error)) (any, error) {
+	singletons.mut.Lock()
+	entry, ok := singletons.values[name]
+	if !ok {
+		entry = &singleton{}
+		singletons.values[name] = entry
+	}
+	singletons.mut.Unlock()
+	entry.mut.Lock()
+	defer entry.mut.Unlock()
+	if entry.done {
+		return entry.value, nil
+	}
+	value, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	entry.value = value
+	entry.done = true
+	return value, nil
+}
+
+// Scope returns the data a subquery sees when it is evaluated for the
+// current row: the keys of the row and, under `<-`, the data of the
+// enclosing query. Rows are never written to, as the same input can be
+// read by several executions at once.
+func Scope(query *Query, current Map) Map {
+	scope := make(Map, len(current)+1)
+	for key, value := range current {
+		scope[key] = value
+	}
+	scope["<-"] = query.data
+	return scope
+}
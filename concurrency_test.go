@@ -0,0 +1,126 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func concurrencyTestData() Map {
+	return Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "a", "team": "x"},
+			Map{"id": 2.0, "name": "b", "team": "y"},
+			Map{"id": 3.0, "name": "c", "team": "x"},
+		},
+		"teams": []any{Map{"team": "x"}},
+		"meta":  Map{"ip": "127.0.0.1", "owner": 2.0},
+	}
+}
+
+func TestQuery_ExecConcurrently(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterFunction("slow", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		time.Sleep(time.Millisecond)
+		return a[0], nil
+	})
+	registry.RegisterFunction("constant", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		return "c", nil
+	})
+	test := []struct {
+		Name     string
+		Query    string
+		Expected string
+	}{
+		{Name: "Subquery", Query: "SELECT id, (SELECT ip FROM `<-root.meta`) AS ip FROM `root.users`", Expected: "[map[id:1 ip:[map[ip:127.0.0.1]]] map[id:2 ip:[map[ip:127.0.0.1]]] map[id:3 ip:[map[ip:127.0.0.1]]]]"},
+		{Name: "Backward Comparison", Query: "SELECT id FROM `root.users` WHERE id = `<-root.meta.owner`", Expected: "[map[id:2]]"},
+		{Name: "Exists", Query: "SELECT id FROM `root.users` WHERE EXISTS (SELECT * FROM `<-root.teams` WHERE team = 'x')", Expected: "[map[id:1] map[id:3]]"},
+		{Name: "Once", Query: "SELECT id, ONCE.constant() AS c FROM `root.users`", Expected: "[map[c:c id:1] map[c:c id:2] map[c:c id:3]]"},
+		{Name: "Global", Query: "SELECT id, GLOBAL.constant() AS c FROM `root.users`", Expected: "[map[c:c id:1] map[c:c id:2] map[c:c id:3]]"},
+		{Name: "Async", Query: "SELECT id, ASYNC.slow(name) AS s FROM `root.users`", Expected: "[map[id:1 s:a] map[id:2 s:b] map[id:3 s:c]]"},
+		{Name: "Aggregate", Query: "SELECT COUNT(*) AS c, SUM(id) AS s FROM `root.users`", Expected: "[map[c:3 s:6]]"},
+		{Name: "Group By", Query: "SELECT team, COUNT(*) AS c FROM `root.users` GROUP BY team ORDER BY team", Expected: "[map[c:2 team:x] map[c:1 team:y]]"},
+		{Name: "CTE", Query: "WITH x AS (SELECT id FROM `root.users` WHERE team = 'x') SELECT * FROM x", Expected: "[map[id:1] map[id:3]]"},
+		{Name: "Recursive CTE", Query: "WITH RECURSIVE t(n) AS (SELECT 1 AS n FROM dual UNION ALL SELECT n + 1 FROM t WHERE n < 3) SELECT * FROM t", Expected: "[map[n:1] map[n:2] map[n:3]]"},
+		{Name: "Delete", Query: "DELETE FROM `root.users` WHERE team = 'x'", Expected: "[map[affected:2 document:map[meta:map[ip:127.0.0.1 owner:2] teams:[map[team:x]] users:[map[id:2 name:b team:y]]]]]"},
+		{Name: "Select All", Query: "SELECT * FROM `root.users` WHERE EXISTS (SELECT * FROM `<-root.teams`)", Expected: "[map[id:1 name:a team:x] map[id:2 name:b team:y] map[id:3 name:c team:x]]"},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			data := concurrencyTestData()
+			snapshot := fmt.Sprintf("%v", data)
+			query, err := New(data, test.Query, Wrapped(), WithRegistry(registry))
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			plan, err := Compile(test.Query, Wrapped(), WithRegistry(registry))
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			wg := sync.WaitGroup{}
+			for i := 0; i < 8; i++ {
+				wg.Add(3)
+				go func() {
+					defer wg.Done()
+					rs, err := query.Exec()
+					if err != nil {
+						t.Errorf("%v", err)
+						return
+					}
+					if out := fmt.Sprintf("%v", rs); out != test.Expected {
+						t.Errorf("expected %s but found %s", test.Expected, out)
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					rs, err := plan.Exec(data)
+					if err != nil {
+						t.Errorf("%v", err)
+						return
+					}
+					if out := fmt.Sprintf("%v", rs); out != test.Expected {
+						t.Errorf("expected %s but found %s", test.Expected, out)
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					rows, err := query.Rows()
+					if err != nil {
+						t.Errorf("%v", err)
+						return
+					}
+					defer rows.Close()
+					rs := make([]any, 0)
+					for rows.Next() {
+						rs = append(rs, rows.Row())
+					}
+					if err := rows.Err(); err != nil {
+						t.Errorf("%v", err)
+						return
+					}
+					if out := fmt.Sprintf("%v", rs); out != test.Expected {
+						t.Errorf("expected %s but found %s", test.Expected, out)
+					}
+				}()
+			}
+			wg.Wait()
+			if out := fmt.Sprintf("%v", data); out != snapshot {
+				t.Fatalf("the input was modified. expected %s but found %s", snapshot, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+// DefaultMaxRecursion is the number of times the recursive member of a
+// recursive CTE can run when the MaxRecursion option is not set
+const DefaultMaxRecursion = 100
+
+// RecursiveUnion returns the UNION of a CTE declared in a WITH RECURSIVE
+// clause when its right side, the recursive member, refers to the CTE. The
+// left side is the anchor and cannot refer to the CTE. CTEs that never
+// refer to themselves are evaluated as usual and nil is returned.
+func RecursiveUnion(cte *sqlparser.CommonTableExpr) (*sqlparser.Union, error) {
+	name := cte.ID.String()
+	union, ok := cte.Subquery.Select.(*sqlparser.Union)
+	if !ok {
+		if RefersTo(cte.Subquery.Select, name) {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("recursive CTE %s must be a UNION of an anchor and a recursive member", name))
+		}
+		return nil, nil
+	}
+	if !RefersTo(union.Right, name) {
+		if RefersTo(union.Left, name) {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("the anchor of recursive CTE %s cannot refer to it", name))
+		}
+		return nil, nil
+	}
+	if RefersTo(union.Left, name) {
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("the anchor of recursive CTE %s cannot refer to it", name))
+	}
+	if len(union.OrderBy) != 0 || union.Limit != nil {
+		return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("recursive CTE %s cannot be sorted or limited", name))
+	}
+	return union, nil
+}
+
+// RefersTo reports whether a statement reads from the table with the given
+// name, including from its subqueries
+func RefersTo(node sqlparser.SQLNode, name string) bool {
+	found := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if tableName, ok := node.(sqlparser.TableName); ok && tableName.Qualifier.IsEmpty() && tableName.Name.String() == name {
+			found = true
+		}
+		return !found, nil
+	}, node)
+	return found
+}
+
+// ExecRecursiveCte evaluates a recursive CTE. The anchor runs once and the
+// recursive member runs again and again, each time reading the rows the
+// previous run produced under the name of the CTE, until it produces no
+// rows. With UNION, rows that were already produced are dropped, which
+// stops cycles in the data. With UNION ALL every row is kept, and the
+// MaxRecursion option is what stops a cycle.
+func ExecRecursiveCte(query *Query, cte *sqlparser.CommonTableExpr, union *sqlparser.Union) ([]any, error) {
+	name := cte.ID.String()
+	err := CheckDepth(query, query.depth+1)
+	if err != nil {
+		return nil, err
+	}
+	recursive := newQuery(query.options)
+	err = Build(recursive, union.Right)
+	if err != nil {
+		return nil, err
+	}
+	anchor, err := query.subquery(query.data, union.Left)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := anchor.execAndPostProcess()
+	if err != nil {
+		return nil, err
+	}
+	anchorNames, recursiveNames, columns, err := RecursiveColumns(union, cte.Columns)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	working, err := RecursiveRows(rs, union.Distinct, anchorNames, columns, seen)
+	if err != nil {
+		return nil, err
+	}
+	result := append(make([]any, 0, len(working)), working...)
+	max := query.options.limits.maxRecursion
+	if max == 0 {
+		max = DefaultMaxRecursion
+	}
+	for iteration := 1; len(working) != 0; iteration++ {
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
+		if max > 0 && iteration > max {
+			return nil, RECURSION_LIMIT_EXCEEDED.Describe(fmt.Sprintf("recursive CTE %s did not end after %d iterations", name, max))
+		}
+		// Every run reads the rows of the previous run, never the
+		// whole result, under the name of the CTE
+		data := make(Map, len(query.data))
+		for key, value := range query.data {
+			data[key] = value
+		}
+		data[name] = working
+		run := recursive.fork(data)
+		run.inherit(query)
+		run.depth = query.depth + 1
+		err := ExecFrom(run)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := run.execAndPostProcess()
+		if err != nil {
+			return nil, err
+		}
+		working, err = RecursiveRows(rs, union.Distinct, recursiveNames, columns, seen)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, working...)
+	}
+	return result, nil
+}
+
+// RecursiveColumns matches the columns of the anchor and the recursive
+// member of a recursive CTE by position, as UNION does. The columns are
+// named after the column list of the CTE or, without one, after the anchor.
+// No names are returned when either side selects `*`, in which case rows
+// are kept as they are selected.
+func RecursiveColumns(union *sqlparser.Union, columns sqlparser.Columns) ([]string, []string, []string, error) {
+	anchorNames, anchorErr := SelectNames(union.Left)
+	recursiveNames, recursiveErr := SelectNames(union.Right)
+	if len(columns) == 0 && (anchorErr != nil || recursiveErr != nil) {
+		return nil, nil, nil, nil
+	}
+	if anchorErr != nil {
+		return nil, nil, nil, anchorErr
+	}
+	if recursiveErr != nil {
+		return nil, nil, nil, recursiveErr
+	}
+	if len(anchorNames) != len(recursiveNames) {
+		return nil, nil, nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("the anchor selects %d columns but the recursive member selects %d", len(anchorNames), len(recursiveNames)))
+	}
+	names := anchorNames
+	if len(columns) != 0 {
+		if len(columns) != len(anchorNames) {
+			return nil, nil, nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("expected %d columns but the query selects %d", len(columns), len(anchorNames)))
+		}
+		names = make([]string, len(columns))
+		for index, column := range columns {
+			names[index] = column.String()
+		}
+	}
+	return anchorNames, recursiveNames, names, nil
+}
+
+// RecursiveRows prepares the rows produced by a run of a recursive CTE.
+// Columns are renamed from the names they are selected under to the names
+// of the CTE, and for UNION the rows that were seen before are dropped.
+func RecursiveRows(rs any, distinct bool, from []string, to []string, seen map[string]bool) ([]any, error) {
+	if rs == nil {
+		return nil, nil
+	}
+	array, err := AsArray(rs)
+	if err != nil {
+		return nil, err
+	}
+	if to != nil {
+		array, err = RenameColumns(array, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !distinct {
+		return array, nil
+	}
+	slice := make([]any, 0, len(array))
+	for _, item := range array {
+		key := StructuralKey(item)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		slice = append(slice, item)
+	}
+	return slice, nil
+}
+
+// RenameCteColumns renames the keys of the rows of a CTE after its column
+// list, matching the columns with the expressions of the SELECT clause by
+// position. The rows are left as they are when there is no column list.
+func RenameCteColumns(rs any, statement sqlparser.SelectStatement, columns sqlparser.Columns) (any, error) {
+	if len(columns) == 0 || rs == nil {
+		return rs, nil
+	}
+	names, err := SelectNames(statement)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) != len(columns) {
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("expected %d columns but the query selects %d", len(columns), len(names)))
+	}
+	array, err := AsArray(rs)
+	if err != nil {
+		return nil, err
+	}
+	to := make([]string, len(columns))
+	for index, column := range columns {
+		to[index] = column.String()
+	}
+	return RenameColumns(array, names, to)
+}
+
+// RenameColumns copies the rows keeping only the given keys, renamed
+// position by position
+func RenameColumns(rows []any, from []string, to []string) ([]any, error) {
+	slice := make([]any, len(rows))
+	for index, item := range rows {
+		row, ok := item.(Map)
+		if !ok {
+			return nil, INVALID_TYPE.Describe(fmt.Sprintf("expected an object but found %T", item))
+		}
+		renamed := make(Map, len(to))
+		for position, name := range to {
+			renamed[name] = row[from[position]]
+		}
+		slice[index] = renamed
+	}
+	return slice, nil
+}
+
+// SelectNames returns the keys the expressions of the SELECT clause of a
+// statement are stored under, in order. The first SELECT of a UNION names
+// its columns.
+func SelectNames(statement sqlparser.SelectStatement) ([]string, error) {
+	switch statement := statement.(type) {
+	case *sqlparser.Union:
+		{
+			return SelectNames(statement.Left)
+		}
+	case *sqlparser.Select:
+		{
+			names := make([]string, 0, len(statement.SelectExprs))
+			for _, expr := range statement.SelectExprs {
+				aliasedExpr, ok := expr.(*sqlparser.AliasedExpr)
+				if !ok {
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("a column list cannot be matched with %s", sqlparser.String(expr)))
+				}
+				names = append(names, SelectName(aliasedExpr))
+			}
+			return names, nil
+		}
+	}
+	return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%T is not supported", statement))
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRecursiveCte(t *testing.T) {
+	data := Map{
+		"employees": []any{
+			Map{"id": 1.0, "name": "ceo", "manager": nil},
+			Map{"id": 2.0, "name": "cto", "manager": 1.0},
+			Map{"id": 3.0, "name": "dev", "manager": 2.0},
+			Map{"id": 4.0, "name": "cfo", "manager": 1.0},
+		},
+		"links": []any{
+			Map{"from": 1.0, "to": 2.0},
+			Map{"from": 2.0, "to": 3.0},
+			Map{"from": 3.0, "to": 1.0},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		options []QueryOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Counter",
+			query: "WITH RECURSIVE t(n) AS (SELECT 1 AS n FROM dual UNION ALL SELECT n + 1 FROM t WHERE n < 5) SELECT * FROM t",
+			want:  "[map[n:1] map[n:2] map[n:3] map[n:4] map[n:5]]",
+		},
+		{
+			name:  "Columns Named After Anchor",
+			query: "WITH RECURSIVE t AS (SELECT 1 AS n FROM dual UNION ALL SELECT n * 2 FROM t WHERE n < 8) SELECT n FROM t",
+			want:  "[map[n:1] map[n:2] map[n:4] map[n:8]]",
+		},
+		{
+			name:  "Org Chart",
+			query: "WITH RECURSIVE chart AS (SELECT id, name, 0 AS depth FROM `root.employees` WHERE manager IS NULL UNION ALL SELECT e.id AS id, e.name AS name, c.depth + 1 AS depth FROM `root.employees` e JOIN chart c ON e.manager = c.id) SELECT name, depth FROM chart",
+			want:  "[map[depth:0 name:ceo] map[depth:1 name:cto] map[depth:1 name:cfo] map[depth:2 name:dev]]",
+		},
+		{
+			name:  "Subtree",
+			query: "WITH RECURSIVE team AS (SELECT id FROM `root.employees` WHERE name = 'cto' UNION ALL SELECT e.id AS id FROM `root.employees` e JOIN team t ON e.manager = t.id) SELECT COUNT(*) AS size FROM team",
+			want:  "[map[size:2]]",
+		},
+		{
+			name:  "Union Stops Cycles",
+			query: "WITH RECURSIVE reach AS (SELECT 1 AS node FROM dual UNION SELECT l.to AS node FROM `root.links` l JOIN reach r ON l.from = r.node) SELECT * FROM reach",
+			want:  "[map[node:1] map[node:2] map[node:3]]",
+		},
+		{
+			name:    "Union All Cycle",
+			query:   "WITH RECURSIVE reach AS (SELECT 1 AS node FROM dual UNION ALL SELECT l.to AS node FROM `root.links` l JOIN reach r ON l.from = r.node) SELECT * FROM reach",
+			wantErr: RECURSION_LIMIT_EXCEEDED,
+		},
+		{
+			name:    "Max Recursion",
+			query:   "WITH RECURSIVE t(n) AS (SELECT 1 AS n FROM dual UNION ALL SELECT n + 1 FROM t WHERE n < 5) SELECT * FROM t",
+			options: []QueryOption{MaxRecursion(3)},
+			wantErr: RECURSION_LIMIT_EXCEEDED,
+		},
+		{
+			name:    "Unlimited Recursion",
+			query:   "WITH RECURSIVE t(n) AS (SELECT 1 AS n FROM dual UNION ALL SELECT n + 1 FROM t WHERE n < 200) SELECT COUNT(*) AS c FROM t",
+			options: []QueryOption{MaxRecursion(-1)},
+			want:    "[map[c:200]]",
+		},
+		{
+			name:  "Not Self Referencing",
+			query: "WITH RECURSIVE t AS (SELECT 1 AS a FROM dual) SELECT * FROM t",
+			want:  "[map[a:1]]",
+		},
+		{
+			name:    "Anchor Refers To Itself",
+			query:   "WITH RECURSIVE t AS (SELECT n FROM t UNION ALL SELECT 1 AS n FROM dual) SELECT * FROM t",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Column Count",
+			query:   "WITH RECURSIVE t(n) AS (SELECT 1 AS n FROM dual UNION ALL SELECT n + 1, n FROM t WHERE n < 5) SELECT * FROM t",
+			wantErr: EXPECTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, append([]QueryOption{Wrapped()}, tt.options...)...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,553 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+type (
+	// DmlDefinition is a compiled UPDATE, DELETE or INSERT statement. The
+	// statement modifies the array found at the target path of a copy of
+	// the document, which is returned with the number of affected rows.
+	DmlDefinition struct {
+		statement string
+		table     string
+		target    []string
+		alias     string
+		updates   []DmlUpdate
+		columns   []string
+		values    sqlparser.Values
+		source    *Query
+		rename    []string
+	}
+	// DmlUpdate is an assignment of the SET clause of an UPDATE statement
+	DmlUpdate struct {
+		path []string
+		expr sqlparser.Expr
+	}
+)
+
+// BuildUpdate compiles an UPDATE statement. Assignments may target nested
+// keys, such as `SET address.city = 'x'`, and are all evaluated against the
+// row as it was before the update.
+func BuildUpdate(query *Query, statement *sqlparser.Update) error {
+	if len(statement.TableExprs) != 1 {
+		return UNSUPPORTED_CASE.Describe("failed to build `UPDATE` statement. only one table can be updated")
+	}
+	definition, err := BuildDmlTable(query, "UPDATE", statement.TableExprs[0])
+	if err != nil {
+		return err
+	}
+	for _, update := range statement.Exprs {
+		path, err := BuildTarget(UpdateColumn(update.Name, definition.alias))
+		if err != nil {
+			return Annotate(err, "SET", update.Name)
+		}
+		definition.updates = append(definition.updates, DmlUpdate{
+			path: path,
+			expr: update.Expr,
+		})
+	}
+	query.cteDefinition = statement.With
+	query.whereDefinition = statement.Where
+	return BuildDmlOrder(query, statement.OrderBy, statement.Limit)
+}
+
+// UpdateColumn returns the selector of the key an assignment writes to,
+// without the alias of the table
+func UpdateColumn(column *sqlparser.ColName, alias string) string {
+	keys := make([]string, 0, 3)
+	if !column.Qualifier.Qualifier.IsEmpty() {
+		keys = append(keys, column.Qualifier.Qualifier.String())
+	}
+	if !column.Qualifier.Name.IsEmpty() {
+		keys = append(keys, column.Qualifier.Name.String())
+	}
+	keys = append(keys, column.Name.String())
+	if len(keys) > 1 && len(alias) != 0 && keys[0] == alias {
+		keys = keys[1:]
+	}
+	return strings.Join(keys, ".")
+}
+
+// BuildDelete compiles a DELETE statement
+func BuildDelete(query *Query, statement *sqlparser.Delete) error {
+	if len(statement.TableExprs) != 1 || len(statement.Targets) != 0 {
+		return UNSUPPORTED_CASE.Describe("failed to build `DELETE` statement. only one table can be deleted from")
+	}
+	_, err := BuildDmlTable(query, "DELETE", statement.TableExprs[0])
+	if err != nil {
+		return err
+	}
+	query.cteDefinition = statement.With
+	query.whereDefinition = statement.Where
+	return BuildDmlOrder(query, statement.OrderBy, statement.Limit)
+}
+
+// BuildInsert compiles an INSERT statement. Rows given as VALUES need a
+// column list, while the rows of a query take the column names of its
+// SELECT clause unless a column list renames them by position.
+func BuildInsert(query *Query, statement *sqlparser.Insert) error {
+	if statement.Action != sqlparser.InsertAct || statement.OnDup != nil {
+		return UNSUPPORTED_CASE.Describe("failed to build `INSERT` statement. REPLACE and ON DUPLICATE KEY UPDATE are not supported")
+	}
+	definition, err := BuildDmlTable(query, "INSERT", &sqlparser.AliasedTableExpr{Expr: statement.Table})
+	if err != nil {
+		return err
+	}
+	for _, column := range statement.Columns {
+		definition.columns = append(definition.columns, column.String())
+	}
+	switch rows := statement.Rows.(type) {
+	case sqlparser.Values:
+		{
+			if len(definition.columns) == 0 {
+				return EXPECTATION_FAILED.Describe("failed to build `INSERT` statement. VALUES requires a column list")
+			}
+			for _, tuple := range rows {
+				if len(tuple) != len(definition.columns) {
+					return EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `INSERT` statement. expected %d values but found %d", len(definition.columns), len(tuple)))
+				}
+			}
+			definition.values = rows
+		}
+	case sqlparser.SelectStatement:
+		{
+			source := newQuery(query.options)
+			err := Build(source, rows)
+			if err != nil {
+				return err
+			}
+			definition.source = source
+			if len(definition.columns) == 0 {
+				break
+			}
+			names, err := SelectNames(rows)
+			if err != nil {
+				return err
+			}
+			if len(names) != len(definition.columns) {
+				return EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `INSERT` statement. expected %d columns but found %d", len(definition.columns), len(names)))
+			}
+			definition.rename = names
+		}
+	default:
+		{
+			return UNSUPPORTED_CASE.Describe(fmt.Sprintf("%T is not supported", rows))
+		}
+	}
+	return nil
+}
+
+// BuildDmlTable compiles the table a DML statement modifies
+func BuildDmlTable(query *Query, statement string, tableExpr sqlparser.TableExpr) (*DmlDefinition, error) {
+	aliasedTableExpr, ok := tableExpr.(*sqlparser.AliasedTableExpr)
+	if !ok {
+		return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("failed to build `%s` statement. joins are not supported", statement))
+	}
+	tableName, ok := aliasedTableExpr.Expr.(sqlparser.TableName)
+	if !ok {
+		return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("failed to build `%s` statement. only tables can be modified", statement))
+	}
+	table := tableName.Name.String()
+	if !tableName.Qualifier.IsEmpty() {
+		table = fmt.Sprintf("%s.%s", tableName.Qualifier.String(), table)
+	}
+	target, err := BuildTarget(table)
+	if err != nil {
+		return nil, err
+	}
+	query.dmlDefinition = &DmlDefinition{
+		statement: statement,
+		table:     table,
+		target:    target,
+		alias:     aliasedTableExpr.As.String(),
+	}
+	return query.dmlDefinition, nil
+}
+
+// BuildTarget splits a selector into the keys of the path it reads. Only
+// plain keys can be written to.
+func BuildTarget(selector string) ([]string, error) {
+	selectors, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	path := make([]string, 0, len(selectors))
+	for _, item := range selectors {
+		key, ok := item.(KeySelector)
+		if !ok || key == "<-" || key == "*" || strings.Contains(selector, "::") {
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("cannot write to %s. only paths made of keys can be modified", selector))
+		}
+		path = append(path, string(key))
+	}
+	if len(path) == 0 {
+		return nil, EXPECTATION_FAILED.Describe("expected a path to write to")
+	}
+	return path, nil
+}
+
+// BuildDmlOrder compiles the ORDER BY and LIMIT clauses of UPDATE and
+// DELETE statements, which restrict the rows that are modified
+func BuildDmlOrder(query *Query, orderBy sqlparser.OrderBy, limit *sqlparser.Limit) error {
+	for _, order := range orderBy {
+		query.orderByDefinition = append(query.orderByDefinition, struct {
+			Key   string
+			Value bool
+		}{
+			Key:   sqlparser.String(order.Expr),
+			Value: order.Direction == sqlparser.AscOrder,
+		})
+		query.orderByExpressions = append(query.orderByExpressions, order.Expr)
+	}
+	return BuildLimit(query, limit)
+}
+
+// ExecDml executes a DML statement. The document is copied along the path
+// of the modified array, so the caller's data is never changed.
+func ExecDml(query *Query) (any, error) {
+	definition := query.dmlDefinition
+	value, err := ReadPath(query.document, definition.target)
+	if err != nil {
+		return nil, err
+	}
+	var rows any
+	var affected int
+	switch definition.statement {
+	case "UPDATE":
+		{
+			rows, affected, err = ExecUpdate(query, value)
+		}
+	case "DELETE":
+		{
+			rows, affected, err = ExecDelete(query, value)
+		}
+	case "INSERT":
+		{
+			rows, affected, err = ExecInsert(query, value)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	document := query.document
+	if value != nil || definition.statement == "INSERT" {
+		document, err = WritePath(query.document, definition.target, rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var result any = document
+	if query.options.wrapped {
+		result = document["root"]
+	}
+	if query.options.completed != nil {
+		query.options.completed()
+	}
+	return []any{Map{"affected": affected, "document": result}}, nil
+}
+
+func ExecUpdate(query *Query, value any) (any, int, error) {
+	rows, single, err := DmlRows(query, value)
+	if err != nil {
+		return nil, 0, err
+	}
+	matches, err := DmlMatches(query, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	updated := make([]any, len(rows))
+	copy(updated, rows)
+	for _, index := range matches {
+		row := rows[index].(Map)
+		current := DmlRow(query, row)
+		values := make([]any, len(query.dmlDefinition.updates))
+		for position, update := range query.dmlDefinition.updates {
+			value, err := DmlValue(query, current, update.expr)
+			if err != nil {
+				return nil, 0, AnnotateRow(Annotate(err, "SET", update.expr), index)
+			}
+			values[position] = value
+		}
+		for position, update := range query.dmlDefinition.updates {
+			row, err = WritePath(row, update.path, values[position])
+			if err != nil {
+				return nil, 0, AnnotateRow(err, index)
+			}
+		}
+		updated[index] = row
+	}
+	if single {
+		return updated[0], len(matches), nil
+	}
+	return updated, len(matches), nil
+}
+
+func ExecDelete(query *Query, value any) (any, int, error) {
+	rows, single, err := DmlRows(query, value)
+	if err != nil {
+		return nil, 0, err
+	}
+	if single {
+		return nil, 0, INVALID_TYPE.Describe(fmt.Sprintf("failed to execute `DELETE` statement. %s is an object, not an array", query.dmlDefinition.table))
+	}
+	matches, err := DmlMatches(query, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	deleted := make(map[int]bool, len(matches))
+	for _, index := range matches {
+		deleted[index] = true
+	}
+	slice := make([]any, 0, len(rows)-len(matches))
+	for index, row := range rows {
+		if deleted[index] {
+			continue
+		}
+		slice = append(slice, row)
+	}
+	return slice, len(matches), nil
+}
+
+func ExecInsert(query *Query, value any) (any, int, error) {
+	rows, single, err := DmlRows(query, value)
+	if err != nil {
+		return nil, 0, err
+	}
+	if single {
+		return nil, 0, INVALID_TYPE.Describe(fmt.Sprintf("failed to execute `INSERT` statement. %s is an object, not an array", query.dmlDefinition.table))
+	}
+	inserted, err := InsertRows(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	slice := make([]any, 0, len(rows)+len(inserted))
+	slice = append(slice, rows...)
+	slice = append(slice, inserted...)
+	return slice, len(inserted), nil
+}
+
+// InsertRows evaluates the rows an INSERT statement adds
+func InsertRows(query *Query) ([]any, error) {
+	definition := query.dmlDefinition
+	if definition.source == nil {
+		slice := make([]any, len(definition.values))
+		for index, tuple := range definition.values {
+			row := make(Map, len(tuple))
+			for position, expr := range tuple {
+				value, err := DmlValue(query, Map{}, expr)
+				if err != nil {
+					return nil, AnnotateRow(Annotate(err, "VALUES", expr), index)
+				}
+				row[definition.columns[position]] = value
+			}
+			slice[index] = row
+		}
+		return slice, nil
+	}
+	source := definition.source.fork(query.data)
+	source.inherit(query)
+	err := ExecFrom(source)
+	if err != nil {
+		return nil, err
+	}
+	data, err := source.execAndPostProcess()
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	slice, err := AsArray(data)
+	if err != nil {
+		return nil, err
+	}
+	if definition.rename != nil {
+		return RenameColumns(slice, definition.rename, definition.columns)
+	}
+	return slice, nil
+}
+
+// DmlRows returns the rows of the modified array. An object is a single
+// row, which can only be updated.
+func DmlRows(query *Query, value any) ([]any, bool, error) {
+	if value == nil {
+		return nil, false, nil
+	}
+	if row, ok := Normalize(value).(Map); ok {
+		return []any{row}, true, nil
+	}
+	rows, err := AsArray(value)
+	if err != nil {
+		return nil, false, INVALID_TYPE.Describe(fmt.Sprintf("failed to execute `%s` statement. expected %s to be an array but found %T", query.dmlDefinition.statement, query.dmlDefinition.table, value))
+	}
+	err = CheckInputRows(query, len(rows))
+	if err != nil {
+		return nil, false, err
+	}
+	return rows, false, nil
+}
+
+// DmlMatches returns the indexes of the rows matching the WHERE clause, in
+// the order given by the ORDER BY clause and restricted by LIMIT. Items that
+// are not objects never match.
+func DmlMatches(query *Query, rows []any) ([]int, error) {
+	matches := make([]any, 0)
+	values := make([][]any, 0)
+	for index, row := range rows {
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
+		row, ok := row.(Map)
+		if !ok {
+			continue
+		}
+		current := DmlRow(query, row)
+		isMatch, err := ExecWhere(query, current)
+		if err != nil {
+			return nil, AnnotateRow(err, index)
+		}
+		if !isMatch {
+			continue
+		}
+		orderValues := make([]any, len(query.orderByExpressions))
+		for position, expr := range query.orderByExpressions {
+			value, err := DmlValue(query, current, expr)
+			if err != nil {
+				return nil, AnnotateRow(Annotate(err, "ORDER BY", expr), index)
+			}
+			orderValues[position] = value
+		}
+		matches = append(matches, index)
+		values = append(values, orderValues)
+	}
+	err := SortValues(matches, values, query.orderByDefinition)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if query.offsetDefinition != -1 {
+		offset = query.offsetDefinition
+	}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	matches = matches[offset:]
+	if query.limitDefinition != -1 && query.limitDefinition < len(matches) {
+		matches = matches[:query.limitDefinition]
+	}
+	slice := make([]int, len(matches))
+	for position, index := range matches {
+		slice[position] = index.(int)
+	}
+	return slice, nil
+}
+
+// DmlRow returns the row expressions of a DML statement are evaluated
+// against, which nests the row under the alias of the table if any
+func DmlRow(query *Query, row Map) Map {
+	if len(query.dmlDefinition.alias) == 0 {
+		return row
+	}
+	return Map{query.dmlDefinition.alias: row}
+}
+
+// DmlValue evaluates an expression of a DML statement. The rows are written
+// right away, so the values of asynchronous functions are awaited.
+func DmlValue(query *Query, current Map, expr sqlparser.Expr) (any, error) {
+	value, err := Expr(query, current, expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	value, err = ValueOf(query, current, value)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := value.(*any); !ok {
+		return value, nil
+	}
+	err = query.wait()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pointer, ok := value.(*any)
+		if !ok {
+			return value, nil
+		}
+		value = *pointer
+	}
+}
+
+// ReadPath returns the value found at a path of keys, or nil when a key is
+// missing
+func ReadPath(data Map, path []string) (any, error) {
+	var value any = data
+	for _, key := range path {
+		switch current := Normalize(value).(type) {
+		case nil:
+			{
+				return nil, nil
+			}
+		case Map:
+			{
+				value = current[key]
+			}
+		default:
+			{
+				return nil, INVALID_TYPE.Describe(fmt.Sprintf("cannot read %s from %T", key, current))
+			}
+		}
+	}
+	return value, nil
+}
+
+// WritePath returns a copy of data with the value written at a path of
+// keys. Objects along the path are copied, and created when missing, while
+// everything else is shared with data.
+func WritePath(data Map, path []string, value any) (Map, error) {
+	clone := make(Map, len(data)+1)
+	for key, value := range data {
+		clone[key] = value
+	}
+	if len(path) == 1 {
+		clone[path[0]] = value
+		return clone, nil
+	}
+	var child Map
+	switch current := Normalize(data[path[0]]).(type) {
+	case nil:
+		{
+			child = Map{}
+		}
+	case Map:
+		{
+			child = current
+		}
+	default:
+		{
+			return nil, INVALID_TYPE.Describe(fmt.Sprintf("cannot write %s into %T", path[1], current))
+		}
+	}
+	child, err := WritePath(child, path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	clone[path[0]] = child
+	return clone, nil
+}
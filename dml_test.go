@@ -0,0 +1,155 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDml(t *testing.T) {
+	data := func() Map {
+		return Map{
+			"users": []any{
+				Map{"id": 1.0, "name": "ann", "email": "ann@mail.com", "address": Map{"city": "rome"}},
+				Map{"id": 2.0, "name": "bob", "email": "bob@mail.com"},
+				Map{"id": 3.0, "name": "cid", "email": "cid@mail.com"},
+			},
+			"admins": []any{
+				Map{"id": 9.0, "name": "eve"},
+			},
+			"settings": Map{"theme": "dark"},
+		}
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Update",
+			query: "UPDATE `root.users` SET email = NULL, name = to_upper(name) WHERE id < 3",
+			want:  "[map[affected:2 users:[map[address:map[city:rome] email:<nil> id:1 name:ANN] map[email:<nil> id:2 name:BOB] map[email:cid@mail.com id:3 name:cid]]]]",
+		},
+		{
+			name:  "Update Nested Key",
+			query: "UPDATE `root.users` u SET u.address.city = 'hidden' WHERE u.id = 1",
+			want:  "[map[affected:1 users:[map[address:map[city:hidden] email:ann@mail.com id:1 name:ann] map[email:bob@mail.com id:2 name:bob] map[email:cid@mail.com id:3 name:cid]]]]",
+		},
+		{
+			name:  "Update Reads Old Values",
+			query: "UPDATE `root.users` SET id = id + 10, name = concat(name, id) WHERE id = 3",
+			want:  "[map[affected:1 users:[map[address:map[city:rome] email:ann@mail.com id:1 name:ann] map[email:bob@mail.com id:2 name:bob] map[email:cid@mail.com id:13 name:cid3]]]]",
+		},
+		{
+			name:  "Update Order By Limit",
+			query: "UPDATE `root.users` SET name = 'last' ORDER BY id DESC LIMIT 1",
+			want:  "[map[affected:1 users:[map[address:map[city:rome] email:ann@mail.com id:1 name:ann] map[email:bob@mail.com id:2 name:bob] map[email:cid@mail.com id:3 name:last]]]]",
+		},
+		{
+			name:  "Update Object",
+			query: "UPDATE `root.settings` SET theme = 'light'",
+			want:  "[map[affected:1 settings:map[theme:light]]]",
+		},
+		{
+			name:  "Delete",
+			query: "DELETE FROM `root.users` WHERE name <> 'bob'",
+			want:  "[map[affected:2 users:[map[email:bob@mail.com id:2 name:bob]]]]",
+		},
+		{
+			name:  "Delete Order By Limit",
+			query: "DELETE FROM `root.users` ORDER BY name DESC LIMIT 2",
+			want:  "[map[affected:2 users:[map[address:map[city:rome] email:ann@mail.com id:1 name:ann]]]]",
+		},
+		{
+			name:  "Delete Missing Array",
+			query: "DELETE FROM `root.missing` WHERE id = 1",
+			want:  "[map[affected:0]]",
+		},
+		{
+			name:  "Insert Values",
+			query: "INSERT INTO `root.admins` (id, name) VALUES (10, 'ivy'), (11, to_upper('joe'))",
+			want:  "[map[admins:[map[id:9 name:eve] map[id:10 name:ivy] map[id:11 name:JOE]] affected:2]]",
+		},
+		{
+			name:  "Insert Select",
+			query: "INSERT INTO `root.admins` (id, name) SELECT id + 100, name FROM `root.users` WHERE id > 1",
+			want:  "[map[admins:[map[id:9 name:eve] map[id:102 name:bob] map[id:103 name:cid]] affected:2]]",
+		},
+		{
+			name:  "Insert Creates Array",
+			query: "INSERT INTO `root.logs` (message) VALUES ('created')",
+			want:  "[map[affected:1 logs:[map[message:created]]]]",
+		},
+		{
+			name:  "Cte",
+			query: "INSERT INTO `root.admins` WITH old AS (SELECT id FROM `root.users` WHERE id < 3) SELECT id FROM old",
+			want:  "[map[admins:[map[id:9 name:eve] map[id:1] map[id:2]] affected:2]]",
+		},
+		{
+			name:    "Delete From Object",
+			query:   "DELETE FROM `root.settings`",
+			wantErr: INVALID_TYPE,
+		},
+		{
+			name:    "Values Without Columns",
+			query:   "INSERT INTO `root.admins` VALUES (10, 'ivy')",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Column Count",
+			query:   "INSERT INTO `root.admins` (id) SELECT id, name FROM `root.users`",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Selector Target",
+			query:   "DELETE FROM `root.users[0]`",
+			wantErr: UNSUPPORTED_CASE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := data()
+			original := fmt.Sprintf("%v", input)
+			q, err := New(input, tt.query, Wrapped())
+			if err == nil {
+				var result []any
+				result, err = q.Exec()
+				if err == nil && tt.wantErr == nil {
+					// Only the modified key is compared, next to the count
+					row := result[0].(Map)
+					document := row["document"].(Map)
+					got := Map{"affected": row["affected"]}
+					for key, value := range document {
+						if fmt.Sprintf("%v", value) != fmt.Sprintf("%v", data()[key]) || data()[key] == nil {
+							got[key] = value
+						}
+					}
+					if fmt.Sprintf("%v", []any{got}) != tt.want {
+						t.Errorf("Exec() = %v, want %v", []any{got}, tt.want)
+					}
+					if fmt.Sprintf("%v", input) != original {
+						t.Errorf("the input was modified: %v", input)
+					}
+					return
+				}
+			}
+			if tt.wantErr == nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,504 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+// Explanation is a node in the tree returned by Query.Explain. Stages are
+// listed in the order they are executed.
+type Explanation struct {
+	Stage       string
+	Description string
+	Children    []*Explanation
+}
+
+// Explain describes how the query is executed without executing it
+func (query *Query) Explain() *Explanation {
+	return ExplainQuery(query)
+}
+
+func ExplainQuery(query *Query) *Explanation {
+	explanation := &Explanation{Stage: "QUERY"}
+	if query.cteDefinition != nil {
+		for _, cte := range query.cteDefinition.Ctes {
+			description := fmt.Sprintf("%s (evaluated once, on first use)", cte.ID.String())
+			if query.cteDefinition.Recursive {
+				if union, err := RecursiveUnion(cte); err == nil && union != nil {
+					description = fmt.Sprintf("%s (recursive, evaluated once, on first use)", cte.ID.String())
+				}
+			}
+			explanation.Add(&Explanation{
+				Stage:       "CTE",
+				Description: description,
+				Children:    []*Explanation{ExplainStatement(query, cte.Subquery.Select)},
+			})
+		}
+	}
+	if query.dmlDefinition != nil {
+		explanation.Add(ExplainDml(query.dmlDefinition))
+	}
+	if query.unionDefinition != nil {
+		explanation.Add(&Explanation{
+			Stage:       query.unionDefinition.operator,
+			Description: ExplainSetOperation(query.unionDefinition),
+			Children: []*Explanation{
+				ExplainQuery(query.unionDefinition.left),
+				ExplainQuery(query.unionDefinition.right),
+			},
+		})
+	} else if query.fromDefinition != nil {
+		explanation.Add(ExplainFrom(query, query.fromDefinition))
+	}
+	if query.whereDefinition != nil {
+		explanation.Add(ExplainExpr(query, "WHERE", query.whereDefinition.Expr))
+	}
+	if len(query.groupDefinition) != 0 {
+		keys := make([]string, 0, len(query.groupDefinition))
+		for key := range query.groupDefinition {
+			if expr, ok := query.groupExpressions[key]; ok && sqlparser.String(expr) != key {
+				key = fmt.Sprintf("%s AS %s", ExplainString(expr), key)
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		explanation.Add(&Explanation{Stage: "GROUP BY", Description: strings.Join(keys, ", ")})
+	}
+	if query.havingDefinition != nil {
+		explanation.Add(ExplainExpr(query, "HAVING", query.havingDefinition.Expr))
+	}
+	for _, window := range query.windowDefinitions {
+		explanation.Add(&Explanation{Stage: "WINDOW", Description: ExplainWindow(window)})
+	}
+	if query.selectDefinition != nil {
+		projection := ExplainNodes(query, "SELECT", query.selectDefinition)
+		if IsSelectAllAggregate(query) {
+			projection.Description += " (aggregated over all rows)"
+		}
+		explanation.Add(projection)
+	}
+	if query.distinct {
+		explanation.Add(&Explanation{Stage: "DISTINCT"})
+	}
+	if len(query.orderByDefinition) != 0 {
+		keys := make([]string, 0, len(query.orderByDefinition))
+		for _, orderBy := range query.orderByDefinition {
+			direction := "DESC"
+			if orderBy.Value {
+				direction = "ASC"
+			}
+			keys = append(keys, fmt.Sprintf("%s %s", orderBy.Key, direction))
+		}
+		explanation.Add(&Explanation{Stage: "ORDER BY", Description: strings.Join(keys, ", ")})
+	}
+	if limit := ExplainLimit(query); limit != "" {
+		explanation.Add(&Explanation{Stage: "LIMIT", Description: limit})
+	}
+	return explanation
+}
+
+func ExplainStatement(query *Query, statement sqlparser.Statement) *Explanation {
+	subquery := newQuery(query.options)
+	err := Build(subquery, statement)
+	if err != nil {
+		return &Explanation{Stage: "ERROR", Description: err.Error()}
+	}
+	return ExplainQuery(subquery)
+}
+
+func ExplainFrom(query *Query, tableExpr sqlparser.TableExpr) *Explanation {
+	switch tableExpr := tableExpr.(type) {
+	case *sqlparser.AliasedTableExpr:
+		{
+			alias := ""
+			if !tableExpr.As.IsEmpty() {
+				alias = fmt.Sprintf(" AS %s", tableExpr.As.String())
+			}
+			switch expr := tableExpr.Expr.(type) {
+			case sqlparser.TableName:
+				{
+					name := expr.Name.String()
+					if !expr.Qualifier.IsEmpty() {
+						name = fmt.Sprintf("%s.%s", expr.Qualifier.String(), name)
+					}
+					if query.cteDefinition != nil {
+						for _, cte := range query.cteDefinition.Ctes {
+							if cte.ID.String() == name {
+								return &Explanation{Stage: "SOURCE", Description: fmt.Sprintf("cte %s%s", name, alias)}
+							}
+						}
+					}
+					if name == "dual" {
+						return &Explanation{Stage: "SOURCE", Description: "dual, unless the document has a `dual` key"}
+					}
+					return &Explanation{Stage: "SOURCE", Description: fmt.Sprintf("selector `%s`%s", name, alias)}
+				}
+			case *sqlparser.DerivedTable:
+				{
+					description := strings.TrimPrefix(alias, " ")
+					if expr.Lateral {
+						description = strings.TrimSpace(fmt.Sprintf("LATERAL%s, evaluated for every row on the left", alias))
+					}
+					return &Explanation{
+						Stage:       "DERIVED TABLE",
+						Description: description,
+						Children:    []*Explanation{ExplainStatement(query, expr.Select)},
+					}
+				}
+			default:
+				{
+					return &Explanation{Stage: "SOURCE", Description: ExplainString(tableExpr)}
+				}
+			}
+		}
+	case *sqlparser.JoinTableExpr:
+		{
+			joinType, on := JoinDefinition(tableExpr)
+			description := strings.ToUpper(JoinName(joinType))
+			if on == nil {
+				description = fmt.Sprintf("CROSS %s (nested loop)", description)
+			}
+			if on != nil {
+				description = fmt.Sprintf("%s ON %s (%s)", description, ExplainString(on), ExplainJoinStrategy(tableExpr))
+			}
+			explanation := &Explanation{
+				Stage:       "JOIN",
+				Description: description,
+				Children: []*Explanation{
+					ExplainFrom(query, tableExpr.LeftExpr),
+					ExplainFrom(query, tableExpr.RightExpr),
+				},
+			}
+			if on != nil {
+				explanation.Children = append(explanation.Children, ExplainCalls(query, on)...)
+			}
+			return explanation
+		}
+	default:
+		{
+			return &Explanation{Stage: "SOURCE", Description: ExplainString(tableExpr)}
+		}
+	}
+}
+
+// ExplainJoinStrategy tells how the rows of a join are matched. Columns are
+// attributed to a side by the aliases of the tables. Joins of tables with no
+// alias are only known to be hashable once their rows are read.
+func ExplainJoinStrategy(tableExpr *sqlparser.JoinTableExpr) string {
+	if _, ok := IsLateral(tableExpr.RightExpr); ok {
+		return "nested loop"
+	}
+	_, on := JoinDefinition(tableExpr)
+	leftAliases := TableAliases(tableExpr.LeftExpr)
+	rightAliases := TableAliases(tableExpr.RightExpr)
+	equalities := JoinEqualities(on, leftAliases, rightAliases)
+	if len(equalities) != 0 {
+		conditions := make([]string, len(equalities))
+		for i, equality := range equalities {
+			conditions[i] = fmt.Sprintf("%s = %s", ExplainString(equality.Left), ExplainString(equality.Right))
+		}
+		return fmt.Sprintf("hash join on %s", strings.Join(conditions, " and "))
+	}
+	if len(leftAliases) != 0 && len(rightAliases) != 0 {
+		return "nested loop"
+	}
+	for _, expr := range sqlparser.SplitAndExpression(nil, on) {
+		comparison, ok := expr.(*sqlparser.ComparisonExpr)
+		if ok && comparison.Operator == sqlparser.EqualOp && HasColumns(comparison.Left) && HasColumns(comparison.Right) {
+			return "hash join if the equalities read from different tables, otherwise nested loop"
+		}
+	}
+	return "nested loop"
+}
+
+// HasColumns reports whether an expression reads any column
+func HasColumns(expr sqlparser.Expr) bool {
+	found := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if colName, ok := node.(*sqlparser.ColName); ok && !IsParameter(colName) {
+			found = true
+		}
+		return !found, nil
+	}, expr)
+	return found
+}
+
+func ExplainExpr(query *Query, stage string, expr sqlparser.Expr) *Explanation {
+	return &Explanation{
+		Stage:       stage,
+		Description: ExplainString(expr),
+		Children:    ExplainCalls(query, expr),
+	}
+}
+
+func ExplainNodes(query *Query, stage string, node sqlparser.SQLNode) *Explanation {
+	return &Explanation{
+		Stage:       stage,
+		Description: ExplainString(node),
+		Children:    ExplainCalls(query, node),
+	}
+}
+
+// ExplainCalls lists the function calls and subqueries of an expression
+// along with the strategy each function call is executed with
+func ExplainCalls(query *Query, node sqlparser.SQLNode) []*Explanation {
+	explanations := make([]*Explanation, 0)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			{
+				explanations = append(explanations, &Explanation{
+					Stage:    "SUBQUERY",
+					Children: []*Explanation{ExplainStatement(query, node.Select)},
+				})
+				return false, nil
+			}
+		case *sqlparser.FuncExpr:
+			{
+				if _, ok := rewriteMarkers[node.Name.Lowered()]; ok && node.Qualifier.IsEmpty() {
+					return true, nil
+				}
+				explanations = append(explanations, &Explanation{
+					Stage:       "FUNCTION",
+					Description: fmt.Sprintf("%s using %s execution", ExplainString(node), ExecutionStrategy(query, node)),
+				})
+			}
+		case *sqlparser.NTHValueExpr:
+			{
+				aggrFunc, ok := WindowAggregate(node)
+				if !ok {
+					break
+				}
+				explanations = append(explanations, &Explanation{
+					Stage:       "FUNCTION",
+					Description: fmt.Sprintf("%s using AGGREGATE execution per window frame", ExplainString(aggrFunc)),
+				})
+				for _, arg := range aggrFunc.GetArgs() {
+					explanations = append(explanations, ExplainCalls(query, arg)...)
+				}
+				return false, nil
+			}
+		case sqlparser.AggrFunc:
+			{
+				scope := "over all rows, once"
+				if len(query.groupDefinition) != 0 {
+					scope = "per group"
+				}
+				explanations = append(explanations, &Explanation{
+					Stage:       "FUNCTION",
+					Description: fmt.Sprintf("%s using AGGREGATE execution %s", ExplainString(node), scope),
+				})
+			}
+		}
+		return true, nil
+	}, node)
+	return explanations
+}
+
+// rewriteMarker is the SQL a function the rewrites wrap an expression in
+// stands for. The expression follows the keyword, in parentheses when
+// parenthesized is set.
+type rewriteMarker struct {
+	keyword       string
+	parenthesized bool
+}
+
+// rewriteMarkers are the functions ExplainString writes back as the SQL
+// they were rewritten from
+var rewriteMarkers = map[string]rewriteMarker{
+	AnyQuantifier:    {keyword: "any", parenthesized: true},
+	AllQuantifier:    {keyword: "all", parenthesized: true},
+	SymmetricBetween: {keyword: "symmetric"},
+}
+
+// ExplainString prints a node the way it was written, turning the
+// functions the rewrites wrap expressions in back into SQL:
+//
+//	a > genql_all((SELECT b FROM t))   => a > all (select b from t)
+//	a BETWEEN genql_symmetric(b) AND c => a between symmetric b and c
+func ExplainString(node sqlparser.SQLNode) string {
+	str := sqlparser.String(node)
+	tokens, err := Tokenize(str)
+	if err != nil {
+		return str
+	}
+	edits := NewEdits()
+	for i := range tokens {
+		marker, ok := rewriteMarkers[strings.ToLower(tokens[i].Text)]
+		open := tokens.Next(i)
+		if !ok || tokens[i].Kind != WordToken || !tokens.IsSymbol(open, "(") || tokens.IsSymbol(tokens.Previous(i), ".") {
+			continue
+		}
+		close, err := tokens.Close(open)
+		if err != nil {
+			return str
+		}
+		inner := tokens.Next(open)
+		if marker.parenthesized && tokens.IsSymbol(inner, "(") {
+			if end, err := tokens.Close(inner); err == nil && tokens.Next(end) == close {
+				edits.Replace(tokens[i].Start, tokens[inner].Start, marker.keyword+" ")
+				edits.Replace(tokens[close].Start, tokens[close].End, "")
+				continue
+			}
+		}
+		if marker.parenthesized {
+			edits.Replace(tokens[i].Start, tokens[open].Start, marker.keyword+" ")
+			continue
+		}
+		edits.Replace(tokens[i].Start, tokens[inner].Start, marker.keyword+" ")
+		edits.Replace(tokens[close].Start, tokens[close].End, "")
+	}
+	return edits.Apply(str)
+}
+
+// ExecutionStrategy returns the name of the strategy a function call is
+// executed with, as described in the README
+func ExecutionStrategy(query *Query, expr *sqlparser.FuncExpr) string {
+	name := expr.Name.Lowered()
+	if name == "await" {
+		return "AWAIT"
+	}
+	strategy := strings.ToUpper(expr.Qualifier.String())
+	if strategy == "" {
+		strategy = "SCOPED"
+	}
+	if query.Registry().IsImmediateFunction(name) {
+		return fmt.Sprintf("%s (immediate)", strategy)
+	}
+	return strategy
+}
+
+func ExplainLimit(query *Query) string {
+	parts := make([]string, 0)
+	if query.limitParameter != nil {
+		parts = append(parts, ExplainString(query.limitParameter))
+	} else if query.limitDefinition != -1 {
+		parts = append(parts, fmt.Sprintf("%d", query.limitDefinition))
+	}
+	if query.offsetParameter != nil {
+		parts = append(parts, fmt.Sprintf("OFFSET %s", ExplainString(query.offsetParameter)))
+	} else if query.offsetDefinition > 0 {
+		parts = append(parts, fmt.Sprintf("OFFSET %d", query.offsetDefinition))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (explanation *Explanation) Add(child *Explanation) {
+	explanation.Children = append(explanation.Children, child)
+}
+
+// Map returns the explanation as a document, which is how the result of an
+// EXPLAIN statement is returned
+func (explanation *Explanation) Map() Map {
+	mapper := Map{
+		"stage": explanation.Stage,
+	}
+	if explanation.Description != "" {
+		mapper["description"] = explanation.Description
+	}
+	if len(explanation.Children) != 0 {
+		children := make([]any, len(explanation.Children))
+		for index, child := range explanation.Children {
+			children[index] = child.Map()
+		}
+		mapper["children"] = children
+	}
+	return mapper
+}
+
+func (explanation *Explanation) String() string {
+	builder := strings.Builder{}
+	explanation.write(&builder, 0)
+	return builder.String()
+}
+
+func (explanation *Explanation) write(builder *strings.Builder, depth int) {
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString(explanation.Stage)
+	if explanation.Description != "" {
+		builder.WriteString(" ")
+		builder.WriteString(explanation.Description)
+	}
+	builder.WriteString("\n")
+	for _, child := range explanation.Children {
+		child.write(builder, depth+1)
+	}
+}
+
+// ExplainWindow describes a window function call, showing aggregates as
+// they are written rather than as they are carried by the parser
+func ExplainWindow(window *Window) string {
+	description := ExplainString(window.Expr)
+	if aggrFunc, ok := WindowAggregate(window.Expr); ok {
+		description = fmt.Sprintf("%s %s", ExplainString(aggrFunc), ExplainString(window.Expr.(*sqlparser.NTHValueExpr).OverClause))
+	}
+	if len(window.Partition) == 0 {
+		return fmt.Sprintf("%s (computed over all rows)", description)
+	}
+	return fmt.Sprintf("%s (computed per partition)", description)
+}
+
+// ExplainSetOperation describes how the rows of both branches of a set
+// operation are combined
+func ExplainSetOperation(union *UnionDefinition) string {
+	switch union.operator {
+	case "INTERSECT":
+		{
+			if union.distinct {
+				return "distinct rows of the first branch found in the second"
+			}
+			return "rows of the first branch matched one to one in the second"
+		}
+	case "EXCEPT":
+		{
+			if union.distinct {
+				return "distinct rows of the first branch not found in the second"
+			}
+			return "rows of the first branch left after removing the matches of the second"
+		}
+	}
+	if union.distinct {
+		return "concatenation of both branches without duplicates"
+	}
+	return "concatenation of both branches"
+}
+
+// ExplainDml describes the array a DML statement modifies and, for UPDATE
+// and INSERT, where the new values come from
+func ExplainDml(dml *DmlDefinition) *Explanation {
+	description := fmt.Sprintf("`%s`", dml.table)
+	if len(dml.alias) != 0 {
+		description = fmt.Sprintf("%s AS %s", description, dml.alias)
+	}
+	explanation := &Explanation{Stage: dml.statement, Description: description + " (on a copy of the document)"}
+	if len(dml.updates) != 0 {
+		assignments := make([]string, 0, len(dml.updates))
+		for _, update := range dml.updates {
+			assignments = append(assignments, fmt.Sprintf("%s = %s", strings.Join(update.path, "."), ExplainString(update.expr)))
+		}
+		explanation.Add(&Explanation{Stage: "SET", Description: strings.Join(assignments, ", ")})
+	}
+	if dml.values != nil {
+		explanation.Add(&Explanation{Stage: "VALUES", Description: fmt.Sprintf("%d rows of %s", len(dml.values), strings.Join(dml.columns, ", "))})
+	}
+	if dml.source != nil {
+		explanation.Add(ExplainQuery(dml.source))
+	}
+	return explanation
+}
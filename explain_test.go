@@ -0,0 +1,186 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQuery_Explain(t *testing.T) {
+	test := []struct {
+		Name     string
+		Query    string
+		Expected string
+	}{
+		{
+			Name:  "Select",
+			Query: "SELECT DISTINCT name, to_lower(role) AS role FROM `root.users` AS u WHERE id > ? ORDER BY name DESC LIMIT 10 OFFSET 5",
+			Expected: `QUERY
+  SOURCE selector ` + "`root.users`" + ` AS u
+  WHERE id > :v1
+  SELECT ` + "`name`" + `, to_lower(role) as role
+    FUNCTION to_lower(role) using SCOPED (immediate) execution
+  DISTINCT
+  ORDER BY name DESC
+  LIMIT 10 OFFSET 5
+`,
+		},
+		{
+			Name:  "Join",
+			Query: "SELECT a.id, ASYNC.fetch(b.id) AS x FROM `root.a` AS a LEFT JOIN `root.b` AS b ON a.id = b.id",
+			Expected: `QUERY
+  JOIN LEFT JOIN ON a.id = b.id (hash join on a.id = b.id)
+    SOURCE selector ` + "`root.a`" + ` AS a
+    SOURCE selector ` + "`root.b`" + ` AS b
+  SELECT a.id, ASYNC.fetch(b.id) as x
+    FUNCTION ASYNC.fetch(b.id) using ASYNC execution
+`,
+		},
+		{
+			Name:  "Group",
+			Query: "WITH t AS (SELECT * FROM `root.items`) SELECT category, SUM(price) AS total FROM t GROUP BY category HAVING total > 10",
+			Expected: `QUERY
+  CTE t (evaluated once, on first use)
+    QUERY
+      SOURCE selector ` + "`root.items`" + `
+      SELECT *
+  SOURCE cte t
+  GROUP BY category
+  HAVING total > 10
+  SELECT category, sum(price) as total
+    FUNCTION sum(price) using AGGREGATE execution per group
+`,
+		},
+		{
+			Name:  "Subquery",
+			Query: "SELECT name FROM (SELECT * FROM `root.users`) AS u WHERE EXISTS (SELECT 1 FROM dual)",
+			Expected: `QUERY
+  DERIVED TABLE AS u
+    QUERY
+      SOURCE selector ` + "`root.users`" + `
+      SELECT *
+  WHERE exists (select 1 from dual)
+    SUBQUERY
+      QUERY
+        SOURCE dual, unless the document has a ` + "`dual`" + ` key
+        SELECT 1
+  SELECT ` + "`name`" + `
+`,
+		},
+		{
+			Name:  "Union",
+			Query: "SELECT id FROM `root.a` UNION ALL SELECT id FROM `root.b`",
+			Expected: `QUERY
+  UNION concatenation of both branches
+    QUERY
+      SOURCE selector ` + "`root.a`" + `
+      SELECT id
+    QUERY
+      SOURCE selector ` + "`root.b`" + `
+      SELECT id
+  SELECT *
+`,
+		},
+		{
+			Name:  "Except",
+			Query: "SELECT id FROM `root.a` EXCEPT SELECT id FROM `root.b`",
+			Expected: `QUERY
+  EXCEPT distinct rows of the first branch not found in the second
+    QUERY
+      SOURCE selector ` + "`root.a`" + `
+      SELECT id
+    QUERY
+      SOURCE selector ` + "`root.b`" + `
+      SELECT id
+  SELECT *
+`,
+		},
+		{
+			Name:  "Quantifiers",
+			Query: "SELECT id FROM `root.a` WHERE id > ALL (SELECT x FROM `root.b`) AND 'x' = ANY (tags) AND 'genql_any(1)' = ANY (1, 2)",
+			Expected: `QUERY
+  SOURCE selector ` + "`root.a`" + `
+  WHERE id > all (select x from ` + "`root.b`" + `) and 'x' = any (tags) and 'genql_any(1)' = any (1, 2)
+    SUBQUERY
+      QUERY
+        SOURCE selector ` + "`root.b`" + `
+        SELECT x
+  SELECT id
+`,
+		},
+		{
+			Name:  "Symmetric Between",
+			Query: "SELECT id FROM `root.a` WHERE id BETWEEN SYMMETRIC LENGTH(name) + 1 AND 2 AND id NOT BETWEEN SYMMETRIC (1) AND 0",
+			Expected: `QUERY
+  SOURCE selector ` + "`root.a`" + `
+  WHERE id between symmetric LENGTH(` + "`name`" + `) + 1 and 2 and id not between symmetric 1 and 0
+    FUNCTION LENGTH(` + "`name`" + `) using SCOPED execution
+  SELECT id
+`,
+		},
+		{
+			Name:  "Update",
+			Query: "UPDATE `root.users` u SET u.address.city = NULL WHERE u.id = 1",
+			Expected: `QUERY
+  UPDATE ` + "`root.users`" + ` AS u (on a copy of the document)
+    SET address.city = null
+  WHERE u.id = 1
+`,
+		},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			plan, err := Compile(test.Query, Wrapped())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			query, err := plan.Bind(nil)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if out := query.Explain().String(); out != test.Expected {
+				t.Fatalf("expected\n%s\nbut found\n%s", test.Expected, out)
+			}
+		})
+	}
+}
+
+func TestExplainStatement(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0},
+		},
+	}
+	called := false
+	RegisterFunction("test_explain_calls", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	query, err := New(data, "EXPLAIN SELECT test_explain_calls(id) AS x FROM `root.users` WHERE id = 1", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs, err := query.Exec()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if called {
+		t.Fatalf("expected EXPLAIN not to execute the query")
+	}
+	expected := "[map[children:[map[description:selector `root.users` stage:SOURCE] map[description:id = 1 stage:WHERE] map[children:[map[description:test_explain_calls(id) using SCOPED execution stage:FUNCTION]] description:test_explain_calls(id) as x stage:SELECT]] stage:QUERY]]"
+	if out := fmt.Sprintf("%v", rs); out != expected {
+		t.Fatalf("expected %s but found %s", expected, out)
+	}
+}
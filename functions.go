@@ -295,7 +295,7 @@ func ElementAtFunc(query *Query, current Map, functionOptions *FunctionOptions,
 	if len(*slice) > index {
 		return (*slice)[index], nil
 	}
-	return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("index %d is out of range", index))
+	return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("index %d is out of range", index))
 }
 
 //	Gets the value of the only key available in an object and returns an error if multiple
@@ -370,7 +370,7 @@ func ChangeTypeFunc(query *Query, current Map, functionOptions *FunctionOptions,
 		}
 	default:
 		{
-			return nil, UNSUPPORTED_CASE.Extend(fmt.Sprintf("%s is not a valid conversion type", *conversionType))
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%s is not a valid conversion type", *conversionType))
 		}
 	}
 }
@@ -414,33 +414,50 @@ func UnwindFunc(query *Query, current Map, functionOptions *FunctionOptions, arg
 // |   1   |    any     |     result when true      |
 // |   2   |    any     |     result when false     |
 // --------------------------------------------------
+//
+// IF is registered as a lazy function so only the selected branch is
+// evaluated
 func IfFunc(query *Query, current Map, functionOptions *FunctionOptions, args []any) (any, error) {
 	err := Guard(3, args)
 	if err != nil {
 		return nil, err
 	}
-	condition, err := AsType[bool](args[0])
+	value, err := Force(args[0])
 	if err != nil {
 		return nil, err
 	}
-	whenTrue, err := AsType[any](args[1])
+	condition, err := AsType[bool](value)
 	if err != nil {
 		return nil, err
 	}
-	whenFalse, err := AsType[any](args[2])
-	if err != nil {
-		return nil, err
+	// An UNKNOWN condition is not TRUE
+	if condition != nil && *condition {
+		return Force(args[1])
 	}
-	if *condition {
-		if whenTrue == nil {
-			return nil, nil
+	return Force(args[2])
+}
+
+//	Coalesce
+//
+// --------------------------------------------------
+// | index |    type    |       description         |
+// |-------|------------|---------------------------|
+// |   n   |    any     |        candidate          |
+// --------------------------------------------------
+//
+// COALESCE is registered as a lazy function so the candidates after the
+// first non-NULL one are never evaluated
+func CoalesceFunc(query *Query, current Map, functionOptions *FunctionOptions, args []any) (any, error) {
+	for _, arg := range args {
+		value, err := Force(arg)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			return value, nil
 		}
-		return *whenTrue, nil
-	}
-	if whenFalse == nil {
-		return nil, nil
 	}
-	return *whenFalse, nil
+	return nil, nil
 }
 
 //	Fuse
@@ -469,7 +486,7 @@ func FuseFunc(query *Query, current Map, functionOptions *FunctionOptions, args
 		}
 	default:
 		{
-			return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("fuse cannot be used with %T type", rs))
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("fuse cannot be used with %T type", rs))
 		}
 	}
 }
@@ -747,7 +764,7 @@ func HashFunc(query *Query, current Map, functionOptions *FunctionOptions, args
 		}
 	default:
 		{
-			return nil, UNSUPPORTED_CASE.Extend(fmt.Sprintf("%s is not supported", *hashFunction))
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%s is not supported", *hashFunction))
 		}
 	}
 }
@@ -790,7 +807,7 @@ func EncodeFunc(query *Query, current Map, functionOptions *FunctionOptions, arg
 		}
 	default:
 		{
-			return nil, UNSUPPORTED_CASE.Extend(fmt.Sprintf("%s is not supported", *base))
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%s is not supported", *base))
 		}
 	}
 }
@@ -844,7 +861,7 @@ func DecodeFunc(query *Query, current Map, functionOptions *FunctionOptions, arg
 		}
 	default:
 		{
-			return nil, UNSUPPORTED_CASE.Extend(fmt.Sprintf("%s is not supported", *base))
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%s is not supported", *base))
 		}
 	}
 	enc := gob.NewDecoder(&buffer)
@@ -909,33 +926,41 @@ func ToInt(any any) (int, error) {
 }
 
 func init() {
-	RegisterImmediateFunction("sum", SumFunc)
-	RegisterImmediateFunction("avg", AvgFunc)
-	RegisterImmediateFunction("min", MinFunc)
-	RegisterImmediateFunction("max", MaxFunc)
-	RegisterImmediateFunction("count", CountFunc)
-	RegisterFunction("concat", ConcatFunc)
-	RegisterFunction("first", FirstFunc)
-	RegisterFunction("last", LastFunc)
-	RegisterFunction("elementat", ElementAtFunc)
-	RegisterFunction("defaultkey", DefaultKeyFunc)
-	RegisterFunction("changetype", ChangeTypeFunc)
-	RegisterFunction("unwind", UnwindFunc)
-	RegisterFunction("if", IfFunc)
-	RegisterImmediateFunction("fuse", FuseFunc)
-	RegisterImmediateFunction("daterange", DateRangeFunc)
-	RegisterImmediateFunction("constant", ConstantFunc)
-	RegisterImmediateFunction("getvar", GetVarFunc)
-	RegisterImmediateFunction("setvar", SetVarFunc)
-	RegisterImmediateFunction("raise_when", RaiseWhenFunc)
-	RegisterImmediateFunction("raise", RaiseFunc)
-	RegisterImmediateFunction("report_when", ReportWhenFunc)
-	RegisterImmediateFunction("report", ReportFunc)
-	RegisterFunction("hash", HashFunc)
-	RegisterFunction("encode", EncodeFunc)
-	RegisterFunction("decode", DecodeFunc)
-	RegisterImmediateFunction("timestamp", TimestampFunc)
-	RegisterFunction("array", ArrayFunc)
-	RegisterImmediateFunction("to_lower", ToLowerFunc)
-	RegisterImmediateFunction("to_upper", ToUpperFunc)
+	RegisterBuiltInFunctions(defaultRegistry)
+}
+
+// RegisterBuiltInFunctions adds the built-in functions to a registry
+func RegisterBuiltInFunctions(registry *Registry) {
+	registry.RegisterImmediateFunction("sum", SumFunc)
+	registry.RegisterImmediateFunction("avg", AvgFunc)
+	registry.RegisterImmediateFunction("min", MinFunc)
+	registry.RegisterImmediateFunction("max", MaxFunc)
+	registry.RegisterImmediateFunction("count", CountFunc)
+	registry.RegisterFunction("concat", ConcatFunc)
+	registry.RegisterFunction("first", FirstFunc)
+	registry.RegisterFunction("last", LastFunc)
+	registry.RegisterFunction("elementat", ElementAtFunc)
+	registry.RegisterFunction("defaultkey", DefaultKeyFunc)
+	registry.RegisterFunction("changetype", ChangeTypeFunc)
+	registry.RegisterFunction("unwind", UnwindFunc)
+	registry.RegisterLazyFunction("if", IfFunc)
+	registry.RegisterLazyFunction("coalesce", CoalesceFunc)
+	registry.RegisterImmediateFunction("fuse", FuseFunc)
+	registry.RegisterImmediateFunction("daterange", DateRangeFunc)
+	registry.RegisterImmediateFunction("constant", ConstantFunc)
+	registry.RegisterImmediateFunction("getvar", GetVarFunc)
+	registry.RegisterImmediateFunction("setvar", SetVarFunc)
+	registry.RegisterImmediateFunction("raise_when", RaiseWhenFunc)
+	registry.RegisterImmediateFunction("raise", RaiseFunc)
+	registry.RegisterImmediateFunction("report_when", ReportWhenFunc)
+	registry.RegisterImmediateFunction("report", ReportFunc)
+	registry.RegisterFunction("hash", HashFunc)
+	registry.RegisterFunction("encode", EncodeFunc)
+	registry.RegisterFunction("decode", DecodeFunc)
+	registry.RegisterImmediateFunction("timestamp", TimestampFunc)
+	registry.RegisterFunction("array", ArrayFunc)
+	registry.RegisterImmediateFunction("to_lower", ToLowerFunc)
+	registry.RegisterImmediateFunction("to_upper", ToUpperFunc)
+	registry.RegisterTopLevelFunction("mix", Mix)
+	registry.RegisterTopLevelFunction("distinct", Distinct)
 }
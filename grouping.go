@@ -0,0 +1,119 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// StructuralKey encodes a value so that two values have the same key when
+// they hold the same data. Objects are encoded with their keys sorted,
+// arrays element by element and numbers by their value regardless of their
+// Go type, so 1 and 1.0 share a key.
+func StructuralKey(value any) string {
+	buffer := bytes.NewBufferString("")
+	WriteStructuralKey(buffer, value)
+	return buffer.String()
+}
+
+func WriteStructuralKey(buffer *bytes.Buffer, value any) {
+	value = Normalize(value)
+	switch value := value.(type) {
+	case nil:
+		{
+			buffer.WriteByte('z')
+		}
+	case bool:
+		{
+			if value {
+				buffer.WriteByte('t')
+				return
+			}
+			buffer.WriteByte('f')
+		}
+	case string:
+		{
+			WriteString(buffer, 's', value)
+		}
+	case Map:
+		{
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			buffer.WriteByte('{')
+			for _, key := range keys {
+				WriteString(buffer, 'k', key)
+				WriteStructuralKey(buffer, value[key])
+			}
+			buffer.WriteByte('}')
+		}
+	case []any:
+		{
+			buffer.WriteByte('[')
+			for _, item := range value {
+				WriteStructuralKey(buffer, item)
+			}
+			buffer.WriteByte(']')
+		}
+	default:
+		{
+			reflected := reflect.ValueOf(value)
+			switch reflected.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				{
+					buffer.WriteByte('n')
+					buffer.WriteString(strconv.FormatInt(reflected.Int(), 10))
+				}
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				{
+					buffer.WriteByte('n')
+					buffer.WriteString(strconv.FormatUint(reflected.Uint(), 10))
+				}
+			case reflect.Float32, reflect.Float64:
+				{
+					buffer.WriteByte('n')
+					buffer.WriteString(FloatKey(reflected.Float()))
+				}
+			default:
+				{
+					WriteString(buffer, 'o', fmt.Sprintf("%T:%v", value, value))
+				}
+			}
+		}
+	}
+}
+
+// FloatKey formats integral floats the way integers are formatted
+func FloatKey(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1<<63 {
+		return strconv.FormatInt(int64(value), 10)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WriteString writes a length prefixed string, so it cannot be confused
+// with what follows it
+func WriteString(buffer *bytes.Buffer, kind byte, value string) {
+	buffer.WriteByte(kind)
+	buffer.WriteString(strconv.Itoa(len(value)))
+	buffer.WriteByte(':')
+	buffer.WriteString(value)
+}
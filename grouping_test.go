@@ -0,0 +1,100 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+func TestStructuralKey(t *testing.T) {
+	test := []struct {
+		Name  string
+		A     any
+		B     any
+		Equal bool
+	}{
+		{Name: "Numbers", A: 1, B: 1.0, Equal: true},
+		{Name: "Number Types", A: int8(-3), B: int64(-3), Equal: true},
+		{Name: "Unsigned", A: uint(7), B: 7.0, Equal: true},
+		{Name: "Fractions", A: 1.5, B: 1.0, Equal: false},
+		{Name: "Number And String", A: 1, B: "1", Equal: false},
+		{Name: "Nil And String", A: nil, B: "<nil>", Equal: false},
+		{Name: "Objects", A: Map{"a": 1, "b": []any{true}}, B: map[string]any{"b": []any{true}, "a": 1.0}, Equal: true},
+		{Name: "Different Objects", A: Map{"a": 1}, B: Map{"a": 1, "b": nil}, Equal: false},
+		{Name: "Arrays", A: []any{1, "x"}, B: []string{"1", "x"}, Equal: false},
+		{Name: "Nested Arrays", A: []any{[]any{1}, 2}, B: []any{[]any{1, 2}}, Equal: false},
+		{Name: "Strings", A: []any{"ab", "c"}, B: []any{"a", "bc"}, Equal: false},
+		{Name: "Structs", A: struct{ A int }{A: 1}, B: Map{"A": 1.0}, Equal: true},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			a, b := StructuralKey(test.A), StructuralKey(test.B)
+			if (a == b) != test.Equal {
+				t.Fatalf("expected equal to be %v but keys are %q and %q", test.Equal, a, b)
+			}
+		})
+	}
+}
+
+func TestQuery_GroupByLarge(t *testing.T) {
+	rows := make([]any, 0)
+	for i := 0; i < 100000; i++ {
+		rows = append(rows, Map{"id": float64(i), "bucket": []any{i % 50000}})
+	}
+	query, err := New(Map{"rows": rows}, "SELECT bucket, COUNT(*) AS count FROM `root.rows` GROUP BY bucket LIMIT 2", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs, err := query.Exec()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if out := fmt.Sprintf("%v", rs); out != "[map[bucket:[0] count:2] map[bucket:[1] count:2]]" {
+		t.Fatalf("unexpected result %s", out)
+	}
+}
+
+func TestGroupLookup(t *testing.T) {
+	data := Map{
+		"rows": []any{
+			Map{"name": "a", "v": 1.0},
+			Map{"name": "A", "v": 2.0},
+			Map{"name": "b", "v": 3.0},
+			Map{"name": "c", "v": 4.0},
+		},
+	}
+	query, err := New(data, "SELECT CONCAT(name, '!') AS n, SUM(v) AS total FROM `root.rows` GROUP BY CONCAT(name, '!') HAVING CONCAT(name, '!') <> 'c!' ORDER BY CONCAT(name, '!') DESC", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// GROUP BY, SELECT, HAVING and ORDER BY each have their own node
+	if len(query.groupLookup) != 4 {
+		t.Fatalf("expected 4 expressions in the lookup but found %d", len(query.groupLookup))
+	}
+	for expr, key := range query.groupLookup {
+		if key != "CONCAT(`name`, '!')" {
+			t.Fatalf("expected %s to be stored under CONCAT(`name`, '!') but found %s", sqlparser.String(expr), key)
+		}
+	}
+	rs, err := query.Exec()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if out := fmt.Sprintf("%v", rs); out != "[map[n:b! total:3] map[n:a! total:1] map[n:A! total:2]]" {
+		t.Fatalf("unexpected result %s", out)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+type (
+	// JoinEquality is an equality of a join condition with one side reading
+	// from the left table and the other from the right table
+	JoinEquality struct {
+		Left  sqlparser.Expr
+		Right sqlparser.Expr
+	}
+	joinSide int
+)
+
+const (
+	noSide joinSide = iota
+	leftSide
+	rightSide
+	bothSides
+)
+
+// JoinEqualities returns the equalities of a join condition that a hash
+// join can use. Keys are the first selector keys of the columns found in
+// the rows of each side; a key found on both sides makes the column
+// ambiguous, as the right row overrides the left one when they are merged.
+func JoinEqualities(joinExpr sqlparser.Expr, leftKeys map[string]bool, rightKeys map[string]bool) []*JoinEquality {
+	if joinExpr == nil {
+		return nil
+	}
+	equalities := make([]*JoinEquality, 0)
+	for _, expr := range sqlparser.SplitAndExpression(nil, joinExpr) {
+		comparison, ok := expr.(*sqlparser.ComparisonExpr)
+		if !ok || comparison.Operator != sqlparser.EqualOp {
+			continue
+		}
+		left := JoinSideOf(comparison.Left, leftKeys, rightKeys)
+		right := JoinSideOf(comparison.Right, leftKeys, rightKeys)
+		switch {
+		case left == leftSide && right == rightSide:
+			{
+				equalities = append(equalities, &JoinEquality{Left: comparison.Left, Right: comparison.Right})
+			}
+		case left == rightSide && right == leftSide:
+			{
+				equalities = append(equalities, &JoinEquality{Left: comparison.Right, Right: comparison.Left})
+			}
+		}
+	}
+	return equalities
+}
+
+// JoinSideOf tells which side of a join an expression reads from. It is
+// bothSides when the expression cannot be evaluated against a single side,
+// including when it has subqueries, aggregates or functions with an
+// execution strategy, and noSide when it reads no column.
+func JoinSideOf(expr sqlparser.Expr, leftKeys map[string]bool, rightKeys map[string]bool) joinSide {
+	side := noSide
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.ColName:
+			{
+				if IsParameter(node) {
+					return true, nil
+				}
+				current := bothSides
+				if key, ok := FirstKey(node); ok {
+					switch {
+					case leftKeys[key] && rightKeys[key]:
+						{
+							current = bothSides
+						}
+					case leftKeys[key]:
+						{
+							current = leftSide
+						}
+					case rightKeys[key]:
+						{
+							current = rightSide
+						}
+					default:
+						{
+							current = noSide
+						}
+					}
+				}
+				if side == noSide {
+					side = current
+				} else if current != noSide && current != side {
+					side = bothSides
+				}
+			}
+		case *sqlparser.Subquery, *sqlparser.ExistsExpr, sqlparser.AggrFunc:
+			{
+				side = bothSides
+			}
+		case *sqlparser.FuncExpr:
+			{
+				if !node.Qualifier.IsEmpty() || node.Name.Lowered() == "await" {
+					side = bothSides
+				}
+			}
+		}
+		return side != bothSides, nil
+	}, expr)
+	return side
+}
+
+// FirstKey returns the key a column starts reading the row from
+func FirstKey(colName *sqlparser.ColName) (string, bool) {
+	qualifier, name, err := BuildColumnName(colName)
+	if err != nil {
+		return "", false
+	}
+	if len(qualifier) > 0 {
+		name = fmt.Sprintf("%s.%s", qualifier, name)
+	}
+	selector, err := ParseSelector(name)
+	if err != nil || len(selector) == 0 {
+		return "", false
+	}
+	key, ok := selector[0].(KeySelector)
+	return string(key), ok
+}
+
+// JoinCandidates hashes the smaller side of a join on the equalities of
+// its condition and returns, for every row on the left, the indexes of
+// the rows on the right that may match it. The condition is still
+// evaluated for every candidate, so the result is exactly that of the
+// nested loop. It returns false when the join cannot be hashed.
+func JoinCandidates(query *Query, left []any, right []any, equalities []*JoinEquality) ([][]int, bool, error) {
+	if len(equalities) == 0 {
+		return nil, false, nil
+	}
+	leftExprs := make([]sqlparser.Expr, len(equalities))
+	rightExprs := make([]sqlparser.Expr, len(equalities))
+	for i, equality := range equalities {
+		leftExprs[i] = equality.Left
+		rightExprs[i] = equality.Right
+	}
+	candidates := make([][]int, len(left))
+	if len(right) <= len(left) {
+		table := make(map[string][]int)
+		for i, row := range right {
+			key, ok, err := JoinHashKey(query, row.(Map), rightExprs)
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			table[key] = append(table[key], i)
+		}
+		for index, row := range left {
+			key, ok, err := JoinHashKey(query, row.(Map), leftExprs)
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			candidates[index] = table[key]
+		}
+		return candidates, true, nil
+	}
+	table := make(map[string][]int)
+	for index, row := range left {
+		key, ok, err := JoinHashKey(query, row.(Map), leftExprs)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		table[key] = append(table[key], index)
+	}
+	for i, row := range right {
+		key, ok, err := JoinHashKey(query, row.(Map), rightExprs)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		for _, index := range table[key] {
+			candidates[index] = append(candidates[index], i)
+		}
+	}
+	return candidates, true, nil
+}
+
+// JoinHashKey evaluates the expressions of one side of the equalities
+// against a row and returns the key of the row in the hash table
+func JoinHashKey(query *Query, row Map, exprs []sqlparser.Expr) (string, bool, error) {
+	keys := make([]string, len(exprs))
+	for i, expr := range exprs {
+		rs, err := Expr(query, row, expr, nil)
+		if err != nil {
+			return "", false, err
+		}
+		value, err := ValueOf(query, row, rs)
+		if err != nil {
+			return "", false, err
+		}
+		key, ok := HashKey(value)
+		if !ok {
+			return "", false, nil
+		}
+		keys[i] = key
+	}
+	return strings.Join(keys, "\x00"), true, nil
+}
+
+// HashKey returns a key that is the same for every two values compare.Compare
+// or compare.Values finds equal. Numbers are keyed by their integral part, as
+// numbers of different types are compared after converting one to the type
+// of the other, and strings holding a number, read the way compare.Float
+// reads them, are keyed as that number. Any other value is keyed by its
+// string form, which is what it is compared by. Values of the types
+// conversions may wrap around are not hashed, and neither are timestamps,
+// which are equal to strings written in many forms.
+func HashKey(value any) (string, bool) {
+	var str string
+	switch value := value.(type) {
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64, float32, time.Time:
+		{
+			return "", false
+		}
+	case int:
+		{
+			return NumberKey(float64(value)), true
+		}
+	case int64:
+		{
+			return NumberKey(float64(value)), true
+		}
+	case float64:
+		{
+			return NumberKey(value), true
+		}
+	case string:
+		{
+			str = value
+		}
+	default:
+		{
+			str = fmt.Sprintf("%v", value)
+		}
+	}
+	// Numbers are compared with strings by their string form
+	if number, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+		return NumberKey(number), true
+	}
+	return "s" + str, true
+}
+
+// NumberKey keys a number by its integral part. Numbers too large to be
+// held exactly share a single key.
+func NumberKey(number float64) string {
+	if math.IsNaN(number) || math.Abs(number) >= 1<<53 {
+		return "n"
+	}
+	return "n" + strconv.FormatInt(int64(number), 10)
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vedadiyan/genql/compare"
+)
+
+func TestHashKey(t *testing.T) {
+	values := []any{
+		nil, true, false, "true", "<nil>",
+		0, 1, -1, int64(1), 2, 1 << 60,
+		0.0, 0.5, -0.5, 1.0, 1.5, 2.0, 1e6, 1e21, float64(1 << 60),
+		"0", "1", "1.0", "1.5", "1e+06", "1000000", "abc", "", " 1", "2 ", "\t1.5\n",
+		Map{"a": 1.0}, []any{1.0},
+	}
+	for _, a := range values {
+		for _, b := range values {
+			if compare.Compare(a, b) != 0 && compare.Values(a, b) != 0 {
+				continue
+			}
+			keyA, okA := HashKey(a)
+			keyB, okB := HashKey(b)
+			if !okA || !okB {
+				t.Fatalf("expected %#v and %#v to be hashable", a, b)
+			}
+			if keyA != keyB {
+				t.Errorf("%#v and %#v are equal but their keys %q and %q are not", a, b, keyA, keyB)
+			}
+		}
+	}
+	if _, ok := HashKey(int8(1)); ok {
+		t.Errorf("expected int8 not to be hashed")
+	}
+	if _, ok := HashKey(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("expected time.Time not to be hashed")
+	}
+}
+
+func TestHashJoin(t *testing.T) {
+	data := Map{
+		"a": []any{
+			Map{"k": 1.0, "g": "x", "v": 1.0},
+			Map{"k": 2, "g": "y", "v": 2.0},
+			Map{"k": "2", "g": "x", "v": 3.0},
+			Map{"k": nil, "g": "y", "v": 4.0},
+			Map{"k": 1.5, "g": "x", "v": 5.0},
+			Map{"g": "x", "v": 6.0},
+		},
+		"b": []any{
+			Map{"k": 2.0, "g": "x", "w": 1.0},
+			Map{"k": "1", "g": "x", "w": 2.0},
+			Map{"k": 1, "g": "y", "w": 3.0},
+			Map{"k": nil, "g": "y", "w": 4.0},
+			Map{"k": 3.0, "g": "x", "w": 5.0},
+			Map{"k": " 2", "g": "y", "w": 6.0},
+			Map{"k": "1.5 ", "g": "x", "w": 7.0},
+		},
+	}
+	conditions := []string{
+		"a.k = b.k",
+		"b.k = a.k",
+		"a.k = b.k AND a.g = b.g",
+		"a.k = b.k AND a.v < b.w",
+		"a.g = b.g AND b.w > 2",
+	}
+	joins := []string{"JOIN", "LEFT JOIN", "RIGHT JOIN", "FULL JOIN"}
+	for _, join := range joins {
+		for _, condition := range conditions {
+			t.Run(fmt.Sprintf("%s ON %s", join, condition), func(t *testing.T) {
+				query := fmt.Sprintf("SELECT * FROM `root.a` a %s `root.b` b ON %s", join, condition)
+				// The disjunction keeps the condition from being hashed
+				nestedLoop := fmt.Sprintf("SELECT * FROM `root.a` a %s `root.b` b ON (%s) OR 1 = 0", join, condition)
+				expected := execHashJoinTest(t, data, nestedLoop)
+				if out := execHashJoinTest(t, data, query); out != expected {
+					t.Fatalf("expected %s but found %s", expected, out)
+				}
+			})
+		}
+	}
+	t.Run("Timestamps", func(t *testing.T) {
+		data := Map{
+			"a": []any{Map{"t": time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}, Map{"t": time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)}},
+			"b": []any{Map{"t": "2023-01-02"}, Map{"t": "2023-01-03T00:00:00Z"}, Map{"t": "2023-01-04"}},
+		}
+		expected := execHashJoinTest(t, data, "SELECT * FROM `root.a` a JOIN `root.b` b ON (a.t = b.t) OR 1 = 0")
+		if out := execHashJoinTest(t, data, "SELECT * FROM `root.a` a JOIN `root.b` b ON a.t = b.t"); out != expected {
+			t.Fatalf("expected %s but found %s", expected, out)
+		}
+		if strings.Count(expected, "map[a:") != 2 {
+			t.Fatalf("expected every timestamp to match but found %s", expected)
+		}
+	})
+	t.Run("Implicit Aliases", func(t *testing.T) {
+		data := Map{
+			"users":  []any{Map{"id": 1.0, "name": "a"}, Map{"id": 2.0, "name": "b"}},
+			"orders": []any{Map{"id": 2.0, "total": 10.0}, Map{"id": 1.0, "total": 5.0}, Map{"id": 2.0, "total": 1.0}},
+		}
+		expected := execHashJoinTest(t, data, "SELECT users.name AS name, orders.total AS total FROM `root.users` JOIN `root.orders` ON (users.id = orders.id) OR 1 = 0")
+		if out := execHashJoinTest(t, data, "SELECT users.name AS name, orders.total AS total FROM `root.users` JOIN `root.orders` ON users.id = orders.id"); out != expected {
+			t.Fatalf("expected %s but found %s", expected, out)
+		}
+		if expected != "[map[name:a total:5] map[name:b total:10] map[name:b total:1]]" {
+			t.Fatalf("unexpected result %s", expected)
+		}
+	})
+}
+
+func TestHashJoin_Large(t *testing.T) {
+	orders := make([]any, 0)
+	customers := make([]any, 0)
+	for i := 0; i < 20000; i++ {
+		orders = append(orders, Map{"id": float64(i), "cid": float64(i % 5000)})
+		customers = append(customers, Map{"id": float64(i), "tier": float64(i % 3)})
+	}
+	data := Map{"orders": orders, "customers": customers}
+	query, err := New(data, "SELECT o.id AS id FROM `root.orders` o JOIN `root.customers` c ON o.cid = c.id AND c.tier = 0", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs, err := query.Exec()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(rs) != 6668 {
+		t.Fatalf("expected 6668 rows but found %d", len(rs))
+	}
+}
+
+func execHashJoinTest(t *testing.T, data Map, query string) string {
+	q, err := New(data, query, Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs, err := q.Exec()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return fmt.Sprintf("%v", rs)
+}
@@ -15,22 +15,28 @@ package genql
 
 import (
 	"errors"
-	"reflect"
 	"strings"
+
+	"github.com/vedadiyan/genql/compare"
 )
 
 func ValueOf(query *Query, current Map, any any) (any, error) {
 	switch value := any.(type) {
 	case ColumnName:
 		{
-			rs, err := ExecReader(current, string(value))
+			// Backward navigation from a row of the query leads to the data
+			// of the query. Subqueries receive it in their scope instead.
+			if _, ok := current["<-"]; !ok && strings.HasPrefix(string(value), "<-") {
+				current = Map{"<-": query.data}
+			}
+			rs, err := query.Registry().ExecReader(current, string(value))
 			if err != nil {
 				if errors.Is(err, KEY_NOT_FOUND) {
 					return nil, nil
 				}
 				return nil, err
 			}
-			return rs, nil
+			return Normalize(rs), nil
 		}
 	case NeutalString:
 		{
@@ -70,42 +76,42 @@ func AsType[T any](value any) (*T, error) {
 	return new(T), INVALID_CAST
 }
 
+// AsNumber reads a value of any numeric type as a float64. Values read
+// from structs keep their Go types, so an int field is a number as much as
+// a float64 read from JSON is.
+func AsNumber(value any) (*float64, error) {
+	if compare.IsNumber(value) {
+		number := compare.As[float64](value)
+		return &number, nil
+	}
+	return AsType[float64](value)
+}
+
 func AsArray(data any) ([]any, error) {
-	switch data := data.(type) {
+	switch data := Normalize(data).(type) {
 	case []any:
 		{
+			for index, item := range data {
+				if IsNormalized(item) {
+					continue
+				}
+				slice := make([]any, len(data))
+				copy(slice, data[:index])
+				for i := index; i < len(data); i++ {
+					slice[i] = Normalize(data[i])
+				}
+				return slice, nil
+			}
 			return data, nil
 		}
 	case Map:
 		{
 			return []any{data}, nil
 		}
-	default:
-		{
-			t := reflect.TypeOf(data)
-			v := reflect.ValueOf(data)
-			kind := t.Kind()
-			switch kind {
-			case reflect.Array, reflect.Slice:
-				{
-					len := v.Len()
-					slice := make([]any, len)
-					for i := 0; i < len; i++ {
-						slice[i] = v.Index(i).Interface()
-					}
-					return slice, nil
-				}
-			}
-		}
 	}
 	return nil, INVALID_TYPE
 }
 
 func IsImmediateFunction(name string) bool {
-	for _, value := range immediateFunctions {
-		if strings.ToLower(name) == value {
-			return true
-		}
-	}
-	return false
+	return defaultRegistry.IsImmediateFunction(name)
 }
@@ -0,0 +1,78 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+// Thunk is an argument of a lazy function that has not been evaluated yet.
+// Calling it evaluates the argument against the current row. The result is
+// kept, so forcing the same thunk twice evaluates the argument once.
+type Thunk func() (any, error)
+
+// Force returns the value of an argument. Thunks are evaluated, any other
+// value is returned as it is. Lazy functions should read every argument
+// through Force since asynchronous executions still pass evaluated values.
+func Force(value any) (any, error) {
+	thunk, ok := value.(Thunk)
+	if !ok {
+		return value, nil
+	}
+	return thunk()
+}
+
+// LazyFuncArgReader wraps each argument of a function in a thunk instead of
+// evaluating it
+func LazyFuncArgReader(query *Query, current Map, selectExprs sqlparser.SelectExprs) ([]any, error) {
+	slice := make([]any, 0)
+	for _, expr := range selectExprs {
+		aliasedExpr, ok := expr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `FUNCTION ARGUMENT`. expected aliased expression but found %T", expr))
+		}
+		slice = append(slice, NewThunk(query, current, aliasedExpr.Expr))
+	}
+	return slice, nil
+}
+
+// NewThunk creates a thunk that evaluates an expression against a row
+func NewThunk(query *Query, current Map, expr sqlparser.Expr) Thunk {
+	var once sync.Once
+	var value any
+	var err error
+	return func() (any, error) {
+		once.Do(func() {
+			var rs any
+			rs, err = Expr(query, current, expr, nil)
+			if err != nil {
+				return
+			}
+			value, err = ValueOf(query, current, rs)
+		})
+		return value, err
+	}
+}
+
+// FunctionArgReader reads the arguments of a function either as values or,
+// for lazy functions, as thunks
+func FunctionArgReader(query *Query, current Map, selectExprs sqlparser.SelectExprs, lazy bool) ([]any, error) {
+	if lazy {
+		return LazyFuncArgReader(query, current, selectExprs)
+	}
+	return FuncArgReader(query, current, selectExprs)
+}
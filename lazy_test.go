@@ -0,0 +1,107 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLazyEvaluation(t *testing.T) {
+	failure := errors.New("evaluated")
+	registry := NewRegistry()
+	registry.RegisterFunction("fail", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		return nil, failure
+	})
+	counter := 0
+	registry.RegisterFunction("tick", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		counter++
+		return counter, nil
+	})
+	registry.RegisterLazyFunction("twice", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		first, err := Force(a[0])
+		if err != nil {
+			return nil, err
+		}
+		second, err := Force(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return []any{first, second}, nil
+	})
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "a", "nick": nil},
+			Map{"id": 2.0, "name": "b", "nick": "bee"},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{name: "And Short Circuit", query: "SELECT id FROM `root.users` WHERE id > 5 AND fail()", want: "[]"},
+		{name: "And Evaluates Right", query: "SELECT id FROM `root.users` WHERE id > 0 AND fail()", wantErr: failure},
+		{name: "Or Short Circuit", query: "SELECT id FROM `root.users` WHERE id > 0 OR fail()", want: "[map[id:1] map[id:2]]"},
+		{name: "Or Evaluates Right", query: "SELECT id FROM `root.users` WHERE id > 5 OR fail()", wantErr: failure},
+		{name: "Null And Evaluates Right", query: "SELECT id FROM `root.users` WHERE nick = 'bee' AND fail()", wantErr: failure},
+		{name: "If Skips Untaken Branch", query: "SELECT if(id > 1, 'big', 'small') AS s, if(id > 0, name, fail()) AS n FROM `root.users`", want: "[map[n:a s:small] map[n:b s:big]]"},
+		{name: "If Evaluates Taken Branch", query: "SELECT if(id > 1, fail(), name) AS n FROM `root.users`", wantErr: failure},
+		{name: "Coalesce", query: "SELECT coalesce(nick, name, fail()) AS c FROM `root.users`", want: "[map[c:a] map[c:bee]]"},
+		{name: "Coalesce All Null", query: "SELECT coalesce(nick, missing) AS c FROM `root.users` WHERE id = 1", want: "[map[c:<nil>]]"},
+		{name: "Thunk Evaluated Once", query: "SELECT twice(tick()) AS t FROM `root.users` WHERE id = 1", want: "[map[t:[1 1]]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter = 0
+			q, err := New(data, tt.query, Wrapped(), WithRegistry(registry))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestLazyRegistry(t *testing.T) {
+	registry := NewRegistry()
+	if !registry.IsLazyFunction("IF") || !registry.Clone().IsLazyFunction("coalesce") {
+		t.Fatalf("expected IF and COALESCE to be lazy")
+	}
+	registry.RegisterFunction("if", IfFunc)
+	if registry.IsLazyFunction("if") {
+		t.Fatalf("expected RegisterFunction to clear the lazy flag")
+	}
+	value, err := Force(Thunk(func() (any, error) { return "x", nil }))
+	if err != nil || value != "x" {
+		t.Fatalf("Force() = %v, %v", value, err)
+	}
+	if value, _ := Force("y"); value != "y" {
+		t.Fatalf("Force() = %v", value)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+type (
+	// pattern is the regular expression compiled for a LIKE or REGEXP
+	// expression, along with the pattern and escape character it was
+	// compiled from
+	pattern struct {
+		source string
+		escape string
+		regexp *regexp.Regexp
+	}
+	// patterns caches the compiled pattern of each LIKE and REGEXP
+	// expression of a query. Only the last pattern is kept, which covers
+	// literal patterns and patterns read from the same value row after row.
+	patterns struct {
+		values sync.Map
+	}
+)
+
+// CaseSensitiveLike makes LIKE and REGEXP tell upper and lower case apart.
+// Without it, matching ignores case unless the pattern is written as BINARY.
+func CaseSensitiveLike() QueryOption {
+	return func(query *Query) {
+		query.options.caseSensitiveLike = true
+	}
+}
+
+// PatternComparison evaluates LIKE, NOT LIKE, REGEXP (RLIKE) and NOT REGEXP.
+// LIKE matches the whole value, REGEXP matches anywhere in the value. A NULL
+// value or pattern makes the result UNKNOWN.
+func PatternComparison(query *Query, current Map, expr *sqlparser.ComparisonExpr, left any) (any, error) {
+	right := expr.Right
+	caseSensitive := query.options.caseSensitiveLike
+	if convertExpr, ok := right.(*sqlparser.ConvertExpr); ok && convertExpr.Type != nil && strings.EqualFold(convertExpr.Type.Type, "binary") {
+		right = convertExpr.Expr
+		caseSensitive = true
+	}
+	rs, err := Expr(query, current, right, nil)
+	if err != nil {
+		return nil, err
+	}
+	source, err := ValueOf(query, current, rs)
+	if err != nil {
+		return nil, err
+	}
+	if (left == nil || source == nil) && !query.options.strictNulls {
+		return nil, nil
+	}
+	escape := "\\"
+	if expr.Escape != nil {
+		rs, err := Expr(query, current, expr.Escape, nil)
+		if err != nil {
+			return nil, err
+		}
+		value, err := ValueOf(query, current, rs)
+		if err != nil {
+			return nil, err
+		}
+		escape = fmt.Sprintf("%v", value)
+		if len([]rune(escape)) > 1 {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `ESCAPE` clause. expected a single character but found '%s'", escape))
+		}
+	}
+	compiled, err := query.options.patterns.Compile(expr, fmt.Sprintf("%v", source), escape, caseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	match := compiled.MatchString(fmt.Sprintf("%v", left))
+	switch expr.Operator {
+	case sqlparser.NotLikeOp, sqlparser.NotRegexpOp:
+		{
+			return !match, nil
+		}
+	default:
+		{
+			return match, nil
+		}
+	}
+}
+
+// Compile returns the regular expression of a LIKE or REGEXP expression,
+// compiling it only when the pattern differs from the last one seen
+func (patterns *patterns) Compile(expr *sqlparser.ComparisonExpr, source string, escape string, caseSensitive bool) (*regexp.Regexp, error) {
+	if value, ok := patterns.values.Load(expr); ok {
+		if cached := value.(*pattern); cached.source == source && cached.escape == escape {
+			return cached.regexp, nil
+		}
+	}
+	var str string
+	switch expr.Operator {
+	case sqlparser.RegexpOp, sqlparser.NotRegexpOp:
+		{
+			str = source
+		}
+	default:
+		{
+			str = LikeRegexp(source, escape)
+		}
+	}
+	if !caseSensitive {
+		str = "(?i)" + str
+	}
+	compiled, err := regexp.Compile(str)
+	if err != nil {
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `%s` expression. %s", strings.ToUpper(expr.Operator.ToString()), err.Error()))
+	}
+	patterns.values.Store(expr, &pattern{source: source, escape: escape, regexp: compiled})
+	return compiled, nil
+}
+
+// LikeRegexp translates a LIKE pattern into an anchored regular expression.
+// % matches any number of characters and _ matches exactly one, every
+// other character matches itself. A character following the escape
+// character is always taken literally.
+func LikeRegexp(pattern string, escape string) string {
+	escapeRune := rune(0)
+	for _, r := range escape {
+		escapeRune = r
+	}
+	buffer := strings.Builder{}
+	buffer.WriteString("(?s)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escapeRune != 0 && r == escapeRune && i+1 < len(runes):
+			{
+				i++
+				buffer.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+		case r == '%':
+			{
+				buffer.WriteString(".*")
+			}
+		case r == '_':
+			{
+				buffer.WriteString(".")
+			}
+		default:
+			{
+				buffer.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+	}
+	buffer.WriteString("$")
+	return buffer.String()
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+func TestPatternComparison(t *testing.T) {
+	data := Map{
+		"files": []any{
+			Map{"id": 1.0, "name": "a.b", "pattern": "A%"},
+			Map{"id": 2.0, "name": "axb", "pattern": "a_b"},
+			Map{"id": 3.0, "name": "(x)_50%"},
+			Map{"id": 4.0, "name": "Report.PDF", "pattern": "%.pdf"},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		options []QueryOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Metacharacters",
+			query: "SELECT id FROM `root.files` WHERE name LIKE 'a.b%'",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Parentheses",
+			query: "SELECT id FROM `root.files` WHERE name LIKE '(x)%'",
+			want:  "[map[id:3]]",
+		},
+		{
+			name:  "Underscore",
+			query: "SELECT id FROM `root.files` WHERE name LIKE 'a_b'",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Escape",
+			query: "SELECT id FROM `root.files` WHERE name LIKE '%!_50!%' ESCAPE '!'",
+			want:  "[map[id:3]]",
+		},
+		{
+			name:  "Default Escape",
+			query: "SELECT id FROM `root.files` WHERE name LIKE '%\\\\_50\\\\%'",
+			want:  "[map[id:3]]",
+		},
+		{
+			name:  "Not Like",
+			query: "SELECT id FROM `root.files` WHERE name NOT LIKE 'a%'",
+			want:  "[map[id:3] map[id:4]]",
+		},
+		{
+			name:  "Ignores Case",
+			query: "SELECT id FROM `root.files` WHERE name LIKE 'report%'",
+			want:  "[map[id:4]]",
+		},
+		{
+			name:    "Case Sensitive",
+			query:   "SELECT id FROM `root.files` WHERE name LIKE 'report%'",
+			options: []QueryOption{CaseSensitiveLike()},
+			want:    "[]",
+		},
+		{
+			name:  "Binary",
+			query: "SELECT id FROM `root.files` WHERE name LIKE BINARY 'Report%'",
+			want:  "[map[id:4]]",
+		},
+		{
+			name:  "Column Pattern",
+			query: "SELECT id, name LIKE pattern AS r FROM `root.files`",
+			want:  "[map[id:1 r:true] map[id:2 r:true] map[id:3 r:<nil>] map[id:4 r:true]]",
+		},
+		{
+			name:  "Regexp",
+			query: "SELECT id FROM `root.files` WHERE name REGEXP '^a.b$'",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Rlike Matches Anywhere",
+			query: "SELECT id FROM `root.files` WHERE name RLIKE '[0-9]+'",
+			want:  "[map[id:3]]",
+		},
+		{
+			name:  "Not Regexp",
+			query: "SELECT id FROM `root.files` WHERE name NOT REGEXP 'pdf$'",
+			want:  "[map[id:1] map[id:2] map[id:3]]",
+		},
+		{
+			name:    "Regexp Case Sensitive",
+			query:   "SELECT id FROM `root.files` WHERE name REGEXP 'pdf$'",
+			options: []QueryOption{CaseSensitiveLike()},
+			want:    "[]",
+		},
+		{
+			name:    "Invalid Regexp",
+			query:   "SELECT id FROM `root.files` WHERE name REGEXP '(a'",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Invalid Escape",
+			query:   "SELECT id FROM `root.files` WHERE name LIKE 'a%' ESCAPE '!!'",
+			wantErr: EXPECTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, append([]QueryOption{Wrapped()}, tt.options...)...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternCache(t *testing.T) {
+	expr := &sqlparser.ComparisonExpr{Operator: sqlparser.LikeOp}
+	cache := &patterns{}
+	first, err := cache.Compile(expr, "a%", "\\", false)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	second, err := cache.Compile(expr, "a%", "\\", false)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected the pattern to be compiled once")
+	}
+	third, err := cache.Compile(expr, "b%", "\\", false)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if third == first || !third.MatchString("bc") {
+		t.Fatalf("expected a changed pattern to be compiled again")
+	}
+}
+
+func TestLikeRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		escape  string
+		want    string
+	}{
+		{pattern: "a%", escape: "\\", want: "(?s)^a.*$"},
+		{pattern: "a.b_", escape: "\\", want: `(?s)^a\.b.$`},
+		{pattern: `50\%`, escape: "\\", want: "(?s)^50%$"},
+		{pattern: "50#%#", escape: "#", want: "(?s)^50%#$"},
+		{pattern: `a\%`, escape: "", want: `(?s)^a\\.*$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := LikeRegexp(tt.pattern, tt.escape); got != tt.want {
+				t.Errorf("LikeRegexp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
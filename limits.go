@@ -0,0 +1,186 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// limits bound the resources a single execution can use. A zero value
+	// means the resource is not limited.
+	limits struct {
+		maxInputRows  int
+		maxResultRows int
+		maxJoinRows   int
+		maxDepth      int
+		maxRecursion  int
+		maxGoroutines int
+		timeout       time.Duration
+	}
+	// execution holds the state shared by every query taking part in one
+	// execution, including subqueries, CTEs and union branches
+	execution struct {
+		parent     context.Context
+		inputRows  atomic.Int64
+		goroutines atomic.Int64
+	}
+)
+
+// MaxInputRows limits the number of rows read from sources in one execution.
+// Rows read by subqueries count towards the same limit.
+func MaxInputRows(max int) QueryOption {
+	return func(query *Query) {
+		query.options.limits.maxInputRows = max
+	}
+}
+
+// MaxResultRows limits the number of rows an execution can return
+func MaxResultRows(max int) QueryOption {
+	return func(query *Query) {
+		query.options.limits.maxResultRows = max
+	}
+}
+
+// MaxJoinRows limits the number of rows a single join can produce
+func MaxJoinRows(max int) QueryOption {
+	return func(query *Query) {
+		query.options.limits.maxJoinRows = max
+	}
+}
+
+// MaxDepth limits how deeply subqueries, derived tables and CTEs can nest
+func MaxDepth(max int) QueryOption {
+	return func(query *Query) {
+		query.options.limits.maxDepth = max
+	}
+}
+
+// MaxRecursion limits the number of times the recursive member of a
+// recursive CTE can run. It defaults to DefaultMaxRecursion, and a negative
+// value removes the limit.
+func MaxRecursion(max int) QueryOption {
+	return func(query *Query) {
+		query.options.limits.maxRecursion = max
+	}
+}
+
+// MaxGoroutines limits the number of goroutines the ASYNC, SPIN and
+// SPINASYNC strategies can start in one execution
+func MaxGoroutines(max int) QueryOption {
+	return func(query *Query) {
+		query.options.limits.maxGoroutines = max
+	}
+}
+
+// Timeout limits the wall-clock time of an execution
+func Timeout(timeout time.Duration) QueryOption {
+	return func(query *Query) {
+		query.options.limits.timeout = timeout
+	}
+}
+
+// start creates the query that runs a single execution
+func (query *Query) start(ctx context.Context, params []any) (*Query, context.CancelFunc) {
+	run := query.fork(query.data)
+	run.execution = &execution{parent: ctx}
+	cancel := context.CancelFunc(func() {})
+	if query.options.limits.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, query.options.limits.timeout)
+	}
+	run.ctx = ctx
+	if params := BindParams(params); params != nil {
+		run.params = params
+	}
+	return run, cancel
+}
+
+// contextErr returns the error of the execution context. Running out of the
+// time given by the Timeout option is reported as TIME_LIMIT_EXCEEDED.
+func (query *Query) contextErr() error {
+	return query.timeoutErr(query.Context().Err())
+}
+
+// timeoutErr reports an error caused by reaching the deadline set by the
+// Timeout option as TIME_LIMIT_EXCEEDED. The original error is kept as the
+// cause. Deadlines set by the caller's context are left untouched.
+func (query *Query) timeoutErr(err error) error {
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, TIME_LIMIT_EXCEEDED) {
+		return err
+	}
+	if query.execution == nil || query.execution.parent == nil || query.execution.parent.Err() != nil {
+		return err
+	}
+	if query.options.limits.timeout <= 0 {
+		return err
+	}
+	return &Error{
+		Code:    TIME_LIMIT_EXCEEDED,
+		Message: fmt.Sprintf("execution took longer than %s", query.options.limits.timeout),
+		Row:     -1,
+		Err:     err,
+	}
+}
+
+func CheckInputRows(query *Query, rows int) error {
+	max := query.options.limits.maxInputRows
+	if max <= 0 || query.execution == nil {
+		return nil
+	}
+	if total := query.execution.inputRows.Add(int64(rows)); total > int64(max) {
+		return INPUT_LIMIT_EXCEEDED.Describe(fmt.Sprintf("read %d rows but at most %d rows are allowed", total, max))
+	}
+	return nil
+}
+
+func CheckResultRows(query *Query, rows int) error {
+	max := query.options.limits.maxResultRows
+	if max <= 0 || rows <= max {
+		return nil
+	}
+	return RESULT_LIMIT_EXCEEDED.Describe(fmt.Sprintf("at most %d rows can be returned", max))
+}
+
+func CheckJoinRows(query *Query, rows int) error {
+	max := query.options.limits.maxJoinRows
+	if max <= 0 || rows <= max {
+		return nil
+	}
+	return JOIN_LIMIT_EXCEEDED.Describe(fmt.Sprintf("a join can produce at most %d rows", max))
+}
+
+func CheckDepth(query *Query, depth int) error {
+	max := query.options.limits.maxDepth
+	if max <= 0 || depth <= max {
+		return nil
+	}
+	return DEPTH_LIMIT_EXCEEDED.Describe(fmt.Sprintf("subqueries can be nested at most %d levels deep", max))
+}
+
+// CheckGoroutines accounts for a goroutine about to be started by an
+// asynchronous execution strategy
+func CheckGoroutines(query *Query) error {
+	max := query.options.limits.maxGoroutines
+	if max <= 0 || query.execution == nil {
+		return nil
+	}
+	if total := query.execution.goroutines.Add(1); total > int64(max) {
+		return GOROUTINE_LIMIT_EXCEEDED.Describe(fmt.Sprintf("at most %d goroutines can be started", max))
+	}
+	return nil
+}
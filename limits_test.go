@@ -0,0 +1,99 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	RegisterFunction("test_limits_sleep", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		select {
+		case <-time.After(time.Millisecond * 20):
+			return nil, nil
+		case <-fo.Context.Done():
+			return nil, fo.Context.Err()
+		}
+	})
+	RegisterFunction("test_limits_noop", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		return nil, nil
+	})
+	data := Map{
+		"a": []any{Map{"id": 1.0}, Map{"id": 2.0}, Map{"id": 3.0}},
+		"b": []any{Map{"id": 1.0}, Map{"id": 2.0}, Map{"id": 3.0}},
+	}
+	test := []struct {
+		Name    string
+		Query   string
+		Options []QueryOption
+		Error   error
+	}{
+		{Name: "Input", Query: "SELECT * FROM `root.a`", Options: []QueryOption{MaxInputRows(2)}, Error: INPUT_LIMIT_EXCEEDED},
+		{Name: "Input CTE", Query: "WITH x AS (SELECT * FROM `root.b`) SELECT * FROM x", Options: []QueryOption{MaxInputRows(5)}, Error: INPUT_LIMIT_EXCEEDED},
+		{Name: "Input Within", Query: "SELECT * FROM `root.a`", Options: []QueryOption{MaxInputRows(3)}},
+		{Name: "Result", Query: "SELECT * FROM `root.a`", Options: []QueryOption{MaxResultRows(2)}, Error: RESULT_LIMIT_EXCEEDED},
+		{Name: "Result Within", Query: "SELECT * FROM `root.a` LIMIT 2", Options: []QueryOption{MaxResultRows(2)}},
+		{Name: "Join", Query: "SELECT * FROM `root.a` AS a JOIN `root.b` AS b ON a.id >= b.id", Options: []QueryOption{MaxJoinRows(5)}, Error: JOIN_LIMIT_EXCEEDED},
+		{Name: "Join Within", Query: "SELECT * FROM `root.a` AS a JOIN `root.b` AS b ON a.id >= b.id", Options: []QueryOption{MaxJoinRows(6)}},
+		{Name: "Depth", Query: "SELECT * FROM (SELECT * FROM (SELECT * FROM `root.a`) AS x) AS y", Options: []QueryOption{MaxDepth(1)}, Error: DEPTH_LIMIT_EXCEEDED},
+		{Name: "Depth CTE", Query: "WITH x AS (SELECT * FROM (SELECT * FROM `root.a`) AS y) SELECT * FROM x", Options: []QueryOption{MaxDepth(1)}, Error: DEPTH_LIMIT_EXCEEDED},
+		{Name: "Depth Within", Query: "SELECT * FROM (SELECT * FROM (SELECT * FROM `root.a`) AS x) AS y", Options: []QueryOption{MaxDepth(2)}},
+		{Name: "Goroutines", Query: "SELECT SPINASYNC.test_limits_noop(id) FROM `root.a`", Options: []QueryOption{MaxGoroutines(2)}, Error: GOROUTINE_LIMIT_EXCEEDED},
+		{Name: "Goroutines Within", Query: "SELECT ASYNC.test_limits_noop(id) AS x FROM `root.a`", Options: []QueryOption{MaxGoroutines(3)}},
+		{Name: "Timeout", Query: "SELECT test_limits_sleep(id) AS x FROM `root.a`", Options: []QueryOption{Timeout(time.Millisecond * 30)}, Error: TIME_LIMIT_EXCEEDED},
+		{Name: "Timeout Within", Query: "SELECT test_limits_sleep(id) AS x FROM `root.a` LIMIT 1", Options: []QueryOption{Timeout(time.Second)}},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			query, err := New(data, test.Query, append(test.Options, Wrapped())...)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			_, err = query.Exec()
+			if test.Error == nil {
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.Error) {
+				t.Fatalf("expected %v but found %v", test.Error, err)
+			}
+		})
+	}
+}
+
+func TestLimits_Timeout(t *testing.T) {
+	query, err := New(Map{"a": []any{Map{"id": 1.0}}}, "SELECT test_limits_sleep(id) AS x FROM `root.a`", Wrapped(), Timeout(time.Millisecond))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = query.Exec()
+	if !errors.Is(err, TIME_LIMIT_EXCEEDED) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v but found %v", TIME_LIMIT_EXCEEDED, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	query, err = New(Map{"a": []any{Map{"id": 1.0}}}, "SELECT test_limits_sleep(id) AS x FROM `root.a`", Wrapped(), Timeout(time.Second))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = query.ExecContext(ctx)
+	if errors.Is(err, TIME_LIMIT_EXCEEDED) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v but found %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+type (
+	// NamedParam binds a value to a `:name` placeholder when passed to Exec.
+	NamedParam struct {
+		Name  string
+		Value any
+	}
+	Params struct {
+		positional []any
+		named      map[string]any
+	}
+)
+
+func Named(name string, value any) NamedParam {
+	return NamedParam{Name: name, Value: value}
+}
+
+// WithParams sets values for `:name` placeholders. Values passed to Exec
+// through Named take precedence over these.
+func WithParams(params map[string]any) QueryOption {
+	return func(query *Query) {
+		query.options.params = params
+	}
+}
+
+// BindParams splits the arguments of an execution into positional values,
+// used by `?` and `$n` placeholders, and named values.
+func BindParams(params []any) *Params {
+	if len(params) == 0 {
+		return nil
+	}
+	bound := &Params{
+		positional: make([]any, 0, len(params)),
+		named:      make(map[string]any),
+	}
+	for _, param := range params {
+		if param, ok := param.(NamedParam); ok {
+			bound.named[param.Name] = param.Value
+			continue
+		}
+		bound.positional = append(bound.positional, param)
+	}
+	return bound
+}
+
+// IsParameter reports whether an expression is a placeholder. The parser
+// keeps `?` and `:name` as arguments, while `$n` is read as a column name.
+func IsParameter(expr sqlparser.Expr) bool {
+	switch expr := expr.(type) {
+	case sqlparser.Argument:
+		{
+			return true
+		}
+	case *sqlparser.ColName:
+		{
+			_, ok := PositionalParameter(expr)
+			return ok
+		}
+	default:
+		{
+			return false
+		}
+	}
+}
+
+func PositionalParameter(expr *sqlparser.ColName) (int, bool) {
+	if !expr.Qualifier.IsEmpty() {
+		return 0, false
+	}
+	name := expr.Name.String()
+	if !strings.HasPrefix(name, "$") {
+		return 0, false
+	}
+	position, err := strconv.Atoi(name[1:])
+	if err != nil || position < 1 {
+		return 0, false
+	}
+	return position, true
+}
+
+func ParamExpr(query *Query, expr sqlparser.Expr) (any, error) {
+	switch expr := expr.(type) {
+	case sqlparser.Argument:
+		{
+			name := string(expr)
+			if value, ok := query.NamedParam(name); ok {
+				return value, nil
+			}
+			if strings.HasPrefix(name, "v") {
+				if position, err := strconv.Atoi(name[1:]); err == nil {
+					return query.PositionalParam(position)
+				}
+			}
+			return nil, KEY_NOT_FOUND.Describe(fmt.Sprintf("failed to read parameter. :%s is not bound", name))
+		}
+	case *sqlparser.ColName:
+		{
+			position, ok := PositionalParameter(expr)
+			if !ok {
+				return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to read parameter. %s is not a placeholder", expr.Name.String()))
+			}
+			return query.PositionalParam(position)
+		}
+	default:
+		{
+			return nil, UNSUPPORTED_CASE
+		}
+	}
+}
+
+func (query *Query) NamedParam(name string) (any, bool) {
+	if query.params != nil {
+		if value, ok := query.params.named[name]; ok {
+			return Normalize(value), true
+		}
+	}
+	if value, ok := query.options.params[name]; ok {
+		return Normalize(value), true
+	}
+	return nil, false
+}
+
+// PositionalParam returns the value bound to a one-based position
+func (query *Query) PositionalParam(position int) (any, error) {
+	if query.params == nil || position > len(query.params.positional) {
+		return nil, KEY_NOT_FOUND.Describe(fmt.Sprintf("failed to read parameter. position %d is not bound", position))
+	}
+	return Normalize(query.params.positional[position-1]), nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "John", "role": "admin"},
+			Map{"id": 2.0, "name": "Jane", "role": "user"},
+			Map{"id": 3.0, "name": "Jack", "role": "user"},
+		},
+	}
+	test := []struct {
+		Name     string
+		Query    string
+		Options  []QueryOption
+		Params   []any
+		Expected string
+		Error    error
+	}{
+		{Name: "Question Mark", Query: "SELECT name FROM `root.users` WHERE role = ? AND id > ?", Params: []any{"user", 2}, Expected: "[map[name:Jack]]"},
+		{Name: "Dollar", Query: "SELECT name FROM `root.users` WHERE id = $2 OR id = $1", Params: []any{1, 3}, Expected: "[map[name:John] map[name:Jack]]"},
+		{Name: "Named", Query: "SELECT name FROM `root.users` WHERE role = :role", Params: []any{Named("role", "admin")}, Expected: "[map[name:John]]"},
+		{Name: "Option", Query: "SELECT name FROM `root.users` WHERE role = :role", Options: []QueryOption{WithParams(map[string]any{"role": "admin"})}, Expected: "[map[name:John]]"},
+		{Name: "Override", Query: "SELECT name FROM `root.users` WHERE role = :role LIMIT 1", Options: []QueryOption{WithParams(map[string]any{"role": "admin"})}, Params: []any{Named("role", "user")}, Expected: "[map[name:Jane]]"},
+		{Name: "Projection", Query: "SELECT name, ? AS tag FROM `root.users` WHERE id = 1", Params: []any{"x"}, Expected: "[map[name:John tag:x]]"},
+		{Name: "Limit", Query: "SELECT name FROM `root.users` LIMIT ? OFFSET ?", Params: []any{1, 1}, Expected: "[map[name:Jane]]"},
+		{Name: "Subquery", Query: "SELECT `u.name` AS name FROM (SELECT * FROM `root.users` WHERE role = ?) AS u WHERE `u.id` > ?", Params: []any{"user", 2}, Expected: "[map[name:Jack]]"},
+		{Name: "Injection", Query: "SELECT name FROM `root.users` WHERE name = ?", Params: []any{"John' OR '1' = '1"}, Expected: "[]"},
+		{Name: "Unbound", Query: "SELECT name FROM `root.users` WHERE id = ?", Error: KEY_NOT_FOUND},
+		{Name: "Unbound Name", Query: "SELECT name FROM `root.users` WHERE id = :id", Error: KEY_NOT_FOUND},
+		{Name: "Invalid Limit", Query: "SELECT name FROM `root.users` LIMIT ?", Params: []any{"x"}, Error: INVALID_TYPE},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			plan, err := Compile(test.Query, append(test.Options, Wrapped())...)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			rs, err := plan.Exec(data, test.Params...)
+			if test.Error != nil {
+				if err == nil || !strings.HasPrefix(err.Error(), test.Error.Error()) {
+					t.Fatalf("expected %v but found %v", test.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if out := fmt.Sprintf("%v", rs); out != test.Expected {
+				t.Fatalf("expected %s but found %s", test.Expected, out)
+			}
+		})
+	}
+}
+
+func TestParams_Reuse(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "John"},
+			Map{"id": 2.0, "name": "Jane"},
+		},
+	}
+	query, err := New(data, "SELECT name FROM `root.users` WHERE id = ?", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for id, name := range []string{"John", "Jane"} {
+		rs, err := query.Exec(id + 1)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if out := fmt.Sprintf("%v", rs); out != fmt.Sprintf("[map[name:%s]]", name) {
+			t.Fatalf("expected %s but found %s", name, out)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+// Plan is a compiled query that is not bound to any data. A plan is never
+// modified after Compile returns, so it can be shared between goroutines
+// and executed against any number of documents.
+type Plan struct {
+	query *Query
+}
+
+func Compile(query string, options ...QueryOption) (*Plan, error) {
+	q := newQuery(&Options{})
+	for _, option := range options {
+		option(q)
+	}
+	if q.options.postgresEscapingDialect {
+		rs, err := DoubleQuotesToBackTick(query)
+		if err != nil {
+			return nil, err
+		}
+		query = rs
+	}
+	if q.options.idomaticArrays {
+		rs, err := FixIdiomaticArray(query)
+		if err != nil {
+			return nil, err
+		}
+		query = rs
+	}
+	statement, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	err = Build(q, statement)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{query: q}, nil
+}
+
+// Bind attaches data to a new query created from the plan. The FROM clause
+// is resolved every time the returned query is executed. Data can be any Go
+// value that Normalize converts into an object, or anything when the plan
+// is wrapped.
+func (plan *Plan) Bind(data any) (*Query, error) {
+	var document Map
+	if plan.query.options.wrapped {
+		document = Map{"root": data}
+	} else if data != nil {
+		mapper, ok := Normalize(data).(Map)
+		if !ok {
+			return nil, INVALID_TYPE.Describe(fmt.Sprintf("failed to bind data. expected an object but found %T", data))
+		}
+		document = mapper
+	}
+	query := plan.query.fork(document)
+	query.dual = IsDualTable(query)
+	return query, nil
+}
+
+func (plan *Plan) Exec(data any, params ...any) ([]any, error) {
+	query, err := plan.Bind(data)
+	if err != nil {
+		return nil, err
+	}
+	return query.Exec(params...)
+}
+
+func (plan *Plan) ExecContext(ctx context.Context, data any, params ...any) ([]any, error) {
+	query, err := plan.Bind(data)
+	if err != nil {
+		return nil, err
+	}
+	return query.ExecContext(ctx, params...)
+}
+
+// IsDualTable reports whether the FROM clause of the query refers to the
+// `dual` pseudo table, which is the case when no key by that name exists
+func IsDualTable(query *Query) bool {
+	tableExpr, ok := query.fromDefinition.(*sqlparser.AliasedTableExpr)
+	if !ok {
+		return false
+	}
+	tableName, ok := tableExpr.Expr.(sqlparser.TableName)
+	if !ok {
+		return false
+	}
+	if tableName.Qualifier.String() != "" || tableName.Name.String() != "dual" {
+		return false
+	}
+	data, err := query.Registry().ExecReader(query.data, "dual")
+	return err == nil && data == nil
+}
+
+func newQuery(options *Options) *Query {
+	return &Query{
+		offsetDefinition:    -1,
+		limitDefinition:     -1,
+		groupDefinition:     make(GroupDefinition),
+		orderByDefinition:   make(OrderByDefinition, 0),
+		singletonExecutions: newSingletons(),
+		postProcessors:      make([]func() error, 0),
+		options:             options,
+	}
+}
+
+// fork copies the compiled definitions of a query into a new query bound
+// to the given data. The execution state is never shared with the original.
+func (query *Query) fork(data Map) *Query {
+	return &Query{
+		data:                data,
+		distinct:            query.distinct,
+		cteDefinition:       query.cteDefinition,
+		fromDefinition:      query.fromDefinition,
+		unionDefinition:     query.unionDefinition,
+		dmlDefinition:       query.dmlDefinition,
+		selectDefinition:    query.selectDefinition,
+		whereDefinition:     query.whereDefinition,
+		groupDefinition:     query.groupDefinition,
+		groupExpressions:    query.groupExpressions,
+		groupLookup:         query.groupLookup,
+		offsetDefinition:    query.offsetDefinition,
+		limitDefinition:     query.limitDefinition,
+		offsetParameter:     query.offsetParameter,
+		limitParameter:      query.limitParameter,
+		havingDefinition:    query.havingDefinition,
+		orderByDefinition:   query.orderByDefinition,
+		orderByExpressions:  query.orderByExpressions,
+		windowDefinitions:   query.windowDefinitions,
+		windowLookup:        query.windowLookup,
+		singletonExecutions: newSingletons(),
+		postProcessors:      make([]func() error, 0),
+		dual:                query.dual,
+		explain:             query.explain,
+		options:             query.options,
+		ctx:                 query.ctx,
+		params:              query.params,
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		options []QueryOption
+		wantErr bool
+	}{
+		{
+			name:    "Simple Select",
+			query:   "SELECT * FROM users",
+			wantErr: false,
+		},
+		{
+			name:    "Union",
+			query:   "SELECT * FROM users UNION SELECT * FROM admins",
+			wantErr: false,
+		},
+		{
+			name:    "Postgres Dialect",
+			query:   `SELECT * FROM "root.users"`,
+			options: []QueryOption{Wrapped(), PostgresEscapingDialect()},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid SQL",
+			query:   "INVALID SQL",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Compile(tt.query, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && plan.query.data != nil {
+				t.Errorf("Compile() bound data to the plan")
+			}
+		})
+	}
+}
+
+func TestPlan_Exec(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		data  []Map
+		want  []string
+	}{
+		{
+			name:  "Select With Where Clause",
+			query: "SELECT name FROM users WHERE id > 1",
+			data: []Map{
+				{"users": []any{Map{"id": 1, "name": "John"}, Map{"id": 2, "name": "Jane"}}},
+				{"users": []any{Map{"id": 3, "name": "Jack"}}},
+			},
+			want: []string{
+				"[map[name:Jane]]",
+				"[map[name:Jack]]",
+			},
+		},
+		{
+			name:  "Union",
+			query: "SELECT name FROM users UNION ALL SELECT name FROM admins",
+			data: []Map{
+				{"users": []any{Map{"name": "John"}}, "admins": []any{Map{"name": "Jane"}}},
+				{"users": []any{}, "admins": []any{Map{"name": "Jack"}}},
+			},
+			want: []string{
+				"[map[name:John] map[name:Jane]]",
+				"[map[name:Jack]]",
+			},
+		},
+		{
+			name:  "CTE",
+			query: "WITH adults AS (SELECT * FROM users WHERE age >= 18) SELECT name FROM adults",
+			data: []Map{
+				{"users": []any{Map{"name": "John", "age": 20}, Map{"name": "Jane", "age": 10}}},
+				{"users": []any{Map{"name": "Jack", "age": 30}}},
+			},
+			want: []string{
+				"[map[name:John]]",
+				"[map[name:Jack]]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Compile(tt.query)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			for i, data := range tt.data {
+				result, err := plan.Exec(data)
+				if err != nil {
+					t.Fatalf("Exec() error = %v", err)
+				}
+				if fmt.Sprintf("%v", result) != tt.want[i] {
+					t.Errorf("Exec() = %v, want %v", result, tt.want[i])
+				}
+				if _, ok := data["adults"]; ok {
+					t.Errorf("Exec() leaked a CTE into the input document")
+				}
+			}
+		})
+	}
+}
+
+func TestPlan_ExecConcurrently(t *testing.T) {
+	plan, err := Compile("SELECT id FROM users WHERE id = 1")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := Map{"users": []any{Map{"id": 1}, Map{"id": 2}}}
+			result, err := plan.Exec(data)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(result) != 1 {
+				errs <- fmt.Errorf("expected 1 row but found %d", len(result))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
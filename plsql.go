@@ -14,10 +14,12 @@
 package genql
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -43,11 +45,20 @@ type (
 	Fuse             map[string]any
 
 	FunctionOptions struct {
+		Context context.Context
 	}
 
 	ExpressionReaderOptions struct {
 	}
 
+	UnionDefinition struct {
+		left     *Query
+		right    *Query
+		operator string
+		distinct bool
+		columns  []string
+		rename   []string
+	}
 	OrderByDefinition []struct {
 		Key   string
 		Value bool
@@ -56,37 +67,55 @@ type (
 		wrapped                 bool
 		postgresEscapingDialect bool
 		idomaticArrays          bool
+		strictNulls             bool
+		caseSensitiveLike       bool
+		patterns                patterns
 		completed               func()
 		errors                  func(err error)
 		constants               map[string]any
 		vars                    map[string]any
 		varsMut                 sync.RWMutex
+		params                  map[string]any
+		limits                  limits
+		registry                *Registry
 	}
 	Query struct {
 		data Map
 		from []any
 		//processed           []any
 		distinct            bool
+		cteDefinition       *sqlparser.With
+		fromDefinition      sqlparser.TableExpr
+		unionDefinition     *UnionDefinition
+		dmlDefinition       *DmlDefinition
+		document            Map
 		selectDefinition    SelectDefinition
 		whereDefinition     WhereDefinition
 		groupDefinition     GroupDefinition
+		groupExpressions    map[string]sqlparser.Expr
+		groupLookup         map[sqlparser.Expr]string
 		offsetDefinition    int
 		limitDefinition     int
+		offsetParameter     sqlparser.Expr
+		limitParameter      sqlparser.Expr
 		havingDefinition    HavingDefinition
 		orderByDefinition   OrderByDefinition
+		orderByExpressions  []sqlparser.Expr
+		windowDefinitions   []*Window
+		windowLookup        map[sqlparser.Expr]int
 		wg                  sync.WaitGroup
-		singletonExecutions map[string]any
+		singletonExecutions *singletons
 		postProcessors      []func() error
 		dual                bool
+		explain             bool
 		options             *Options
+		ctx                 context.Context
+		params              *Params
+		execution           *execution
+		depth               int
 	}
 )
 
-var (
-	functions          map[string]Function
-	immediateFunctions []string
-)
-
 func Wrapped() QueryOption {
 	return func(query *Query) {
 		query.options.wrapped = true
@@ -105,6 +134,15 @@ func IdomaticArrays() QueryOption {
 	}
 }
 
+// StrictNulls makes NULL operands of AND, OR and NOT, and NULL conditions
+// in WHERE, HAVING, JOIN and CASE, fail the query instead of being read as
+// UNKNOWN
+func StrictNulls() QueryOption {
+	return func(query *Query) {
+		query.options.strictNulls = true
+	}
+}
+
 func CompletedCallback(callback func()) QueryOption {
 	return func(query *Query) {
 		query.options.completed = callback
@@ -129,73 +167,68 @@ func WithVars(vars map[string]any) QueryOption {
 	}
 }
 
-func New(data Map, query string, options ...QueryOption) (*Query, error) {
-	q := &Query{
-		offsetDefinition:    -1,
-		limitDefinition:     -1,
-		groupDefinition:     make(GroupDefinition),
-		orderByDefinition:   make(OrderByDefinition, 0),
-		singletonExecutions: make(map[string]any),
-		postProcessors:      make([]func() error, 0),
-		options:             &Options{},
-	}
-	for _, option := range options {
-		option(q)
-	}
-	switch q.options.wrapped {
-	case true:
-		{
-			q.data = Map{"root": data}
-		}
-	default:
-		{
-			q.data = data
-		}
-	}
-	if q.options.postgresEscapingDialect {
-		rs, err := DoubleQuotesToBackTick(query)
-		if err != nil {
-			return nil, err
-		}
-		query = rs
-	}
-	if q.options.idomaticArrays {
-		rs, err := FixIdiomaticArray(query)
-		if err != nil {
-			return nil, err
-		}
-		query = rs
-	}
-	statement, err := Parse(query)
+func New(data any, query string, options ...QueryOption) (*Query, error) {
+	plan, err := Compile(query, options...)
 	if err != nil {
 		return nil, err
 	}
-	err = Build(q, statement)
+	return plan.Bind(data)
+}
+
+func Prepare(data Map, statement sqlparser.Statement, options *Options) (*Query, error) {
+	return PrepareContext(context.Background(), data, statement, options)
+}
+
+func PrepareContext(ctx context.Context, data Map, statement sqlparser.Statement, options *Options) (*Query, error) {
+	q := newQuery(options)
+	q.data = data
+	q.ctx = ctx
+	err := q.prepare(statement)
 	if err != nil {
 		return nil, err
 	}
 	return q, nil
 }
 
-func Prepare(data Map, statement sqlparser.Statement, options *Options) (*Query, error) {
-	q := &Query{
-		offsetDefinition:    -1,
-		limitDefinition:     -1,
-		groupDefinition:     make(GroupDefinition),
-		orderByDefinition:   make(OrderByDefinition, 0),
-		singletonExecutions: map[string]any{},
-		postProcessors:      make([]func() error, 0),
-		options:             options,
+// subquery prepares a statement nested in the query. The nested query
+// takes part in the same execution as the query.
+func (query *Query) subquery(data Map, statement sqlparser.Statement) (*Query, error) {
+	err := CheckDepth(query, query.depth+1)
+	if err != nil {
+		return nil, err
 	}
+	q := newQuery(query.options)
 	q.data = data
-	err := Build(q, statement)
+	q.inherit(query)
+	q.depth = query.depth + 1
+	err = q.prepare(statement)
 	if err != nil {
 		return nil, err
 	}
 	return q, nil
 }
 
+// inherit makes the query take part in the execution of another query
+func (query *Query) inherit(parent *Query) {
+	query.ctx = parent.ctx
+	query.params = parent.params
+	query.execution = parent.execution
+	query.depth = parent.depth
+}
+
+func (query *Query) prepare(statement sqlparser.Statement) error {
+	err := Build(query, statement)
+	if err != nil {
+		return err
+	}
+	return ExecFrom(query)
+}
+
 func Parse(query string) (Statement, error) {
+	query, err := RewriteQuery(query, JoinEdits, WindowAggregateEdits, SetOperationEdits, BetweenEdits, QuantifierEdits)
+	if err != nil {
+		return nil, err
+	}
 	return sqlparser.Parse(query)
 }
 
@@ -209,50 +242,151 @@ func Build(query *Query, statement Statement) error {
 		{
 			return BuildUnion(query, statement)
 		}
+	case *sqlparser.Update:
+		{
+			return BuildUpdate(query, statement)
+		}
+	case *sqlparser.Delete:
+		{
+			return BuildDelete(query, statement)
+		}
+	case *sqlparser.Insert:
+		{
+			return BuildInsert(query, statement)
+		}
+	case *sqlparser.ExplainStmt:
+		{
+			query.explain = true
+			return Build(query, statement.Statement)
+		}
 	default:
 		{
-			return UNSUPPORTED_CASE.Extend(fmt.Sprintf("%T is not supported", statement))
+			return UNSUPPORTED_CASE.Describe(fmt.Sprintf("%T is not supported", statement))
 		}
 	}
 }
 
 func BuildSelect(query *Query, slct *sqlparser.Select) error {
+	query.cteDefinition = slct.With
+	query.fromDefinition = slct.From[0]
+	query.whereDefinition = slct.Where
 	if len(slct.From) > 1 {
-		return EXPECTATION_FAILED.Extend("this version of gql does not support multiple table selection")
+		fromDefinition, whereDefinition, err := BuildCrossJoin(slct.From, slct.Where)
+		if err != nil {
+			return err
+		}
+		query.fromDefinition, query.whereDefinition = fromDefinition, whereDefinition
 	}
-	err := BuildCte(query, slct.With)
+	query.havingDefinition = slct.Having
+	query.selectDefinition = slct.SelectExprs
+	query.distinct = slct.Distinct
+	err := BuildLimit(query, slct.Limit)
 	if err != nil {
 		return err
 	}
-	err = BuildFrom(query, &slct.From[0])
+	err = BuildGroup(query, &slct.GroupBy)
 	if err != nil {
 		return err
 	}
-	err = BuildLimit(query, slct.Limit)
+	err = BuildOrder(query, &slct.OrderBy)
 	if err != nil {
 		return err
 	}
-	err = BuildGroup(query, &slct.GroupBy)
+	err = BuildWindows(query, slct)
 	if err != nil {
 		return err
 	}
-	err = BuildOrder(query, &slct.OrderBy)
+	BuildGroupLookup(query, slct)
+	return nil
+}
+
+// BuildUnion compiles both sides of the set operation without touching any
+// data. The branches are executed by ExecFrom once the query is bound to a
+// document. Rows of the right branch take the column names of the left one,
+// by position, and ORDER BY refers to the columns of the first SELECT.
+func BuildUnion(query *Query, expr *sqlparser.Union) error {
+	left := newQuery(query.options)
+	err := Build(left, expr.Left)
+	if err != nil {
+		return err
+	}
+	right := newQuery(query.options)
+	err = Build(right, expr.Right)
+	if err != nil {
+		return err
+	}
+	operator := SetOperator(expr)
+	query.cteDefinition = expr.With
+	query.unionDefinition = &UnionDefinition{
+		left:     left,
+		right:    right,
+		operator: operator,
+		distinct: expr.Distinct,
+	}
+	leftColumns, leftErr := SelectNames(expr.Left)
+	rightColumns, rightErr := SelectNames(expr.Right)
+	if leftErr == nil && rightErr == nil {
+		if len(leftColumns) != len(rightColumns) {
+			return EXPECTATION_FAILED.Describe(fmt.Sprintf("the operands of %s have %d and %d columns", operator, len(leftColumns), len(rightColumns)))
+		}
+		if !reflect.DeepEqual(leftColumns, rightColumns) {
+			query.unionDefinition.columns = leftColumns
+			query.unionDefinition.rename = rightColumns
+		}
+	}
+	query.selectDefinition = sqlparser.GetFirstSelect(expr.Left).SelectExprs
+	err = BuildOrder(query, &expr.OrderBy)
+	if err != nil {
+		return err
+	}
+	query.selectDefinition = sqlparser.SelectExprs{
+		&sqlparser.StarExpr{},
+	}
+	err = BuildLimit(query, expr.Limit)
 	if err != nil {
 		return err
 	}
-	query.havingDefinition = slct.Having
-	query.selectDefinition = slct.SelectExprs
-	query.whereDefinition = slct.Where
-	query.distinct = slct.Distinct
 	return nil
 }
 
-func BuildUnion(query *Query, expr *sqlparser.Union) error {
-	leftStatement := expr.Left.(*sqlparser.Select)
-	leftStatement.With = expr.With
-	rightStatement := expr.Right.(*sqlparser.Select)
-	rightStatement.With = expr.With
-	left, err := Prepare(query.data, leftStatement, query.options)
+// ExecFrom resolves the data dependent parts of a built query, that is
+// the common table expressions and the FROM clause, against query.data.
+func ExecFrom(query *Query) error {
+	err := ExecLimit(query)
+	if err != nil {
+		return err
+	}
+	if query.dmlDefinition != nil {
+		// DML statements modify the caller's document, which does not
+		// hold the evaluations of the CTEs
+		query.document = query.data
+	}
+	if query.cteDefinition != nil {
+		// CTE evaluations are stored next to the input keys, so they are
+		// kept in a copy to leave the caller's document untouched
+		data := make(Map, len(query.data))
+		for key, value := range query.data {
+			data[key] = value
+		}
+		query.data = data
+		err = BuildCte(query, query.cteDefinition)
+		if err != nil {
+			return err
+		}
+	}
+	if query.unionDefinition != nil {
+		return ExecUnion(query)
+	}
+	if query.dmlDefinition != nil {
+		return nil
+	}
+	return BuildFrom(query, &query.fromDefinition)
+}
+
+func ExecUnion(query *Query) error {
+	left := query.unionDefinition.left.fork(query.data)
+	left.inherit(query)
+	err := ExecFrom(left)
 	if err != nil {
 		return err
 	}
@@ -260,7 +394,9 @@ func BuildUnion(query *Query, expr *sqlparser.Union) error {
 	if err != nil {
 		return err
 	}
-	right, err := Prepare(query.data, rightStatement, query.options)
+	right := query.unionDefinition.right.fork(query.data)
+	right.inherit(query)
+	err = ExecFrom(right)
 	if err != nil {
 		return err
 	}
@@ -276,18 +412,13 @@ func BuildUnion(query *Query, expr *sqlparser.Union) error {
 	if err != nil {
 		return err
 	}
-
-	slice := make([]any, 0)
-	slice = append(slice, leftDataArray...)
-	slice = append(slice, rightDataArray...)
-	query.from = slice
-	query.selectDefinition = sqlparser.SelectExprs{
-		&sqlparser.StarExpr{},
-	}
-	err = BuildLimit(query, expr.Limit)
-	if err != nil {
-		return err
+	if query.unionDefinition.columns != nil {
+		rightDataArray, err = RenameColumns(rightDataArray, query.unionDefinition.rename, query.unionDefinition.columns)
+		if err != nil {
+			return err
+		}
 	}
+	query.from = SetOperation(query.unionDefinition.operator, query.unionDefinition.distinct, leftDataArray, rightDataArray)
 	return nil
 }
 
@@ -297,8 +428,25 @@ func BuildCte(query *Query, expr *sqlparser.With) error {
 	}
 	for _, cte := range expr.Ctes {
 		copy := *cte
+		if expr.Recursive {
+			union, err := RecursiveUnion(&copy)
+			if err != nil {
+				return err
+			}
+			if union != nil {
+				query.data[copy.ID.String()] = CteEvaluation(func() (any, error) {
+					rs, err := ExecRecursiveCte(query, &copy, union)
+					if err != nil {
+						return nil, Annotate(err, "WITH", copy.ID)
+					}
+					query.data[copy.ID.String()] = rs
+					return rs, nil
+				})
+				continue
+			}
+		}
 		query.data[copy.ID.String()] = CteEvaluation(func() (any, error) {
-			query, err := Prepare(query.data, copy.Subquery.Select, query.options)
+			query, err := query.subquery(query.data, copy.Subquery.Select)
 			if err != nil {
 				return nil, err
 			}
@@ -306,6 +454,10 @@ func BuildCte(query *Query, expr *sqlparser.With) error {
 			if err != nil {
 				return nil, err
 			}
+			rs, err = RenameCteColumns(rs, copy.Subquery.Select, copy.Columns)
+			if err != nil {
+				return nil, Annotate(err, "WITH", copy.ID)
+			}
 			query.data[copy.ID.String()] = rs
 			return rs, nil
 		})
@@ -317,7 +469,9 @@ func BuildLimit(query *Query, limit *sqlparser.Limit) error {
 	if limit == nil {
 		return nil
 	}
-	if limit.Offset != nil {
+	if IsParameter(limit.Offset) {
+		query.offsetParameter = limit.Offset
+	} else if limit.Offset != nil {
 		_, offsetLiteral, err := BuildLiteral(limit.Offset)
 		if err != nil {
 			return err
@@ -328,6 +482,10 @@ func BuildLimit(query *Query, limit *sqlparser.Limit) error {
 		}
 		query.offsetDefinition = offsetNumeric
 	}
+	if IsParameter(limit.Rowcount) {
+		query.limitParameter = limit.Rowcount
+		return nil
+	}
 	_, limitLiteral, err := BuildLiteral(limit.Rowcount)
 	if err != nil {
 		return err
@@ -340,54 +498,300 @@ func BuildLimit(query *Query, limit *sqlparser.Limit) error {
 	return nil
 }
 
+// ExecLimit binds the placeholders used by LIMIT and OFFSET, which are
+// only known once the query is executed
+func ExecLimit(query *Query) error {
+	if query.offsetParameter != nil {
+		offset, err := ExecLimitParameter(query, query.offsetParameter)
+		if err != nil {
+			return err
+		}
+		query.offsetDefinition = offset
+	}
+	if query.limitParameter != nil {
+		limit, err := ExecLimitParameter(query, query.limitParameter)
+		if err != nil {
+			return err
+		}
+		query.limitDefinition = limit
+	}
+	return nil
+}
+
+func ExecLimitParameter(query *Query, expr sqlparser.Expr) (int, error) {
+	value, err := ParamExpr(query, expr)
+	if err != nil {
+		return 0, err
+	}
+	number, err := ToInt(value)
+	if err != nil || number < 0 {
+		return 0, INVALID_TYPE.Describe(fmt.Sprintf("failed to bind `LIMIT` expression. expected a non-negative integer but found %v", value))
+	}
+	return number, nil
+}
+
+// BuildGroup compiles the GROUP BY clause. Columns are read from the rows
+// as they are. Positions refer to the expressions of the SELECT clause, an
+// unqualified column that is not found in the SELECT clause under its own
+// name but is the alias of an expression refers to that expression, and any
+// other expression is evaluated for every row. Expressions are stored in the
+// group rows under their alias or their SQL text.
 func BuildGroup(query *Query, group *sqlparser.GroupBy) error {
 	if group == nil {
 		return nil
 	}
-	for _, i := range *group {
-		qualifier, name, err := BuildColumnName(i)
+	for _, expr := range *group {
+		key, value, err := BuildGroupExpr(query, expr)
 		if err != nil {
-			return nil
+			return Annotate(err, "GROUP BY", expr)
 		}
-		if len(qualifier) == 0 {
-			query.groupDefinition[name] = true
+		query.groupDefinition[key] = true
+		if value == nil {
 			continue
 		}
-		query.groupDefinition[fmt.Sprintf("%s.%s", qualifier, name)] = true
+		if query.groupExpressions == nil {
+			query.groupExpressions = make(map[string]sqlparser.Expr)
+		}
+		query.groupExpressions[key] = value
 	}
 	return nil
 }
 
+// BuildGroupLookup finds the expressions of a statement that are written
+// the same as an expression of the GROUP BY clause. Such expressions read
+// the value stored in the group rows instead of being evaluated again. The
+// lookup is keyed by the nodes themselves, so it is built once and Expr
+// does not have to print an expression to find it.
+func BuildGroupLookup(query *Query, statement sqlparser.SQLNode) {
+	if len(query.groupExpressions) == 0 {
+		return
+	}
+	keys := make(map[string]string)
+	for key, expr := range query.groupExpressions {
+		keys[sqlparser.String(expr)] = key
+	}
+	query.groupLookup = make(map[sqlparser.Expr]string)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		expr, ok := node.(sqlparser.Expr)
+		if !ok || reflect.ValueOf(expr).Kind() != reflect.Pointer {
+			return true, nil
+		}
+		if key, ok := keys[sqlparser.String(expr)]; ok {
+			query.groupLookup[expr] = key
+			return false, nil
+		}
+		return true, nil
+	}, statement)
+}
+
+// GroupLookup returns the key of the group rows an expression is stored
+// under (see BuildGroupLookup)
+func GroupLookup(query *Query, expr sqlparser.Expr) (string, bool) {
+	if len(query.groupLookup) == 0 {
+		return "", false
+	}
+	// Tuples are slices, which cannot be looked up in a map
+	if _, ok := expr.(sqlparser.ValTuple); ok {
+		return "", false
+	}
+	key, ok := query.groupLookup[expr]
+	return key, ok
+}
+
+// BuildGroupExpr returns the key a GROUP BY expression is stored under and,
+// unless it is a plain column, the expression computing it
+func BuildGroupExpr(query *Query, expr sqlparser.Expr) (string, sqlparser.Expr, error) {
+	if aliasedExpr, ok, err := SelectPosition(query, expr); ok || err != nil {
+		if err != nil {
+			return "", nil, err
+		}
+		if IsAggregate(aliasedExpr.Expr) {
+			return "", nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `GROUP BY` clause. position %s refers to an aggregate", sqlparser.String(expr)))
+		}
+		if _, ok := aliasedExpr.Expr.(*sqlparser.ColName); ok {
+			return BuildGroupExpr(query, aliasedExpr.Expr)
+		}
+		return SelectName(aliasedExpr), aliasedExpr.Expr, nil
+	}
+	switch expr := expr.(type) {
+	case *sqlparser.ColName:
+		{
+			qualifier, name, err := BuildColumnName(expr)
+			if err != nil {
+				return "", nil, err
+			}
+			if len(qualifier) != 0 {
+				return fmt.Sprintf("%s.%s", qualifier, name), nil, nil
+			}
+			if aliasedExpr, ok := SelectAlias(query, name); ok {
+				return name, aliasedExpr.Expr, nil
+			}
+			return name, nil, nil
+		}
+	case *sqlparser.Literal, *sqlparser.NullVal, sqlparser.BoolVal:
+		{
+			return "", nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `GROUP BY` clause. cannot group by the constant %s", sqlparser.String(expr)))
+		}
+	}
+	if IsAggregate(expr) {
+		return "", nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `GROUP BY` clause. cannot group by the aggregate %s", sqlparser.String(expr)))
+	}
+	return sqlparser.String(expr), expr, nil
+}
+
+// BuildOrder compiles the ORDER BY clause. Rows are sorted after the SELECT
+// clause, so keys are read from the selected rows: positions and aliases
+// refer to the expressions of the SELECT clause and so do expressions that
+// are selected as they are. Other expressions, and columns that are not
+// selected, are evaluated for every row before it is selected.
 func BuildOrder(query *Query, orderBy *sqlparser.OrderBy) error {
 	if orderBy == nil {
 		return nil
 	}
-	for _, ordeorderBy := range *orderBy {
-		qualifier, columnName, err := BuildColumnName(ordeorderBy.Expr)
+	for _, order := range *orderBy {
+		key, expr, err := BuildOrderExpr(query, order.Expr)
 		if err != nil {
-			return err
-		}
-		if len(qualifier) == 0 {
-			query.orderByDefinition = append(query.orderByDefinition, struct {
-				Key   string
-				Value bool
-			}{
-				Key:   columnName,
-				Value: ordeorderBy.Direction == sqlparser.AscOrder,
-			})
-			continue
+			return Annotate(err, "ORDER BY", order.Expr)
 		}
 		query.orderByDefinition = append(query.orderByDefinition, struct {
 			Key   string
 			Value bool
 		}{
-			Key:   fmt.Sprintf("%s.%s", qualifier, columnName),
-			Value: ordeorderBy.Direction == sqlparser.AscOrder,
+			Key:   key,
+			Value: order.Direction == sqlparser.AscOrder,
 		})
+		query.orderByExpressions = append(query.orderByExpressions, expr)
 	}
 	return nil
 }
 
+// BuildOrderExpr returns the key an ORDER BY expression is read from in the
+// selected rows and, when the value may not be selected, the expression
+// computing it from the row before it is selected
+func BuildOrderExpr(query *Query, expr sqlparser.Expr) (string, sqlparser.Expr, error) {
+	if aliasedExpr, ok, err := SelectPosition(query, expr); ok || err != nil {
+		if err != nil {
+			return "", nil, err
+		}
+		return SelectName(aliasedExpr), nil, nil
+	}
+	switch expr := expr.(type) {
+	case *sqlparser.ColName:
+		{
+			qualifier, name, err := BuildColumnName(expr)
+			if err != nil {
+				return "", nil, err
+			}
+			if len(qualifier) != 0 {
+				name = fmt.Sprintf("%s.%s", qualifier, name)
+			} else if _, ok := SelectAlias(query, name); ok {
+				return name, nil, nil
+			}
+			if query.distinct && !IsSelectedColumn(query, expr) {
+				return "", nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `ORDER BY` clause. for SELECT DISTINCT, %s must appear in the select list", sqlparser.String(expr)))
+			}
+			return name, expr, nil
+		}
+	case *sqlparser.Literal, *sqlparser.NullVal, sqlparser.BoolVal:
+		{
+			return "", nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `ORDER BY` clause. cannot order by the constant %s", sqlparser.String(expr)))
+		}
+	}
+	for _, selectExpr := range query.selectDefinition {
+		aliasedExpr, ok := selectExpr.(*sqlparser.AliasedExpr)
+		if ok && sqlparser.String(aliasedExpr.Expr) == sqlparser.String(expr) {
+			return SelectName(aliasedExpr), nil, nil
+		}
+	}
+	if query.distinct {
+		return "", nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `ORDER BY` clause. for SELECT DISTINCT, %s must appear in the select list", sqlparser.String(expr)))
+	}
+	return sqlparser.String(expr), expr, nil
+}
+
+// IsSelectedColumn reports whether a column is kept by the SELECT clause,
+// either because every column is selected or because it is selected as it
+// is
+func IsSelectedColumn(query *Query, expr *sqlparser.ColName) bool {
+	for _, selectExpr := range query.selectDefinition {
+		switch selectExpr := selectExpr.(type) {
+		case *sqlparser.StarExpr:
+			{
+				return true
+			}
+		case *sqlparser.AliasedExpr:
+			{
+				if sqlparser.String(selectExpr.Expr) == sqlparser.String(expr) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// SelectPosition resolves an integer literal to the expression of the
+// SELECT clause at that position
+func SelectPosition(query *Query, expr sqlparser.Expr) (*sqlparser.AliasedExpr, bool, error) {
+	literal, ok := expr.(*sqlparser.Literal)
+	if !ok || literal.Type != sqlparser.IntVal {
+		return nil, false, nil
+	}
+	position, err := strconv.Atoi(literal.Val)
+	if err != nil || position < 1 || position > len(query.selectDefinition) {
+		return nil, true, EXPECTATION_FAILED.Describe(fmt.Sprintf("position %s is not in the select list", literal.Val))
+	}
+	aliasedExpr, ok := query.selectDefinition[position-1].(*sqlparser.AliasedExpr)
+	if !ok {
+		return nil, true, EXPECTATION_FAILED.Describe(fmt.Sprintf("position %s refers to %s", literal.Val, sqlparser.String(query.selectDefinition[position-1])))
+	}
+	return aliasedExpr, true, nil
+}
+
+// SelectAlias finds the expression of the SELECT clause with the given
+// alias. Columns selected under their own name are not aliases.
+func SelectAlias(query *Query, name string) (*sqlparser.AliasedExpr, bool) {
+	for _, selectExpr := range query.selectDefinition {
+		aliasedExpr, ok := selectExpr.(*sqlparser.AliasedExpr)
+		if !ok || aliasedExpr.As.String() != name {
+			continue
+		}
+		if colName, ok := aliasedExpr.Expr.(*sqlparser.ColName); ok && colName.Qualifier.IsEmpty() && colName.Name.String() == name {
+			return nil, false
+		}
+		return aliasedExpr, true
+	}
+	return nil, false
+}
+
+// SelectName returns the key an expression of the SELECT clause is stored
+// under in the selected rows
+func SelectName(aliasedExpr *sqlparser.AliasedExpr) string {
+	if len(aliasedExpr.As.String()) > 0 {
+		return aliasedExpr.As.String()
+	}
+	return aliasedExpr.ColumnName()
+}
+
+// IsAggregate reports whether an expression calls an aggregate function
+func IsAggregate(expr sqlparser.Expr) bool {
+	found := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node.(type) {
+		case sqlparser.AggrFunc:
+			{
+				found = true
+			}
+		case *sqlparser.Subquery:
+			{
+				return false, nil
+			}
+		}
+		return !found, nil
+	}, expr)
+	return found
+}
+
 func BuildFrom(query *Query, tableExpr *sqlparser.TableExpr) error {
 	switch tableExpr := (*tableExpr).(type) {
 	case *sqlparser.AliasedTableExpr:
@@ -400,83 +804,432 @@ func BuildFrom(query *Query, tableExpr *sqlparser.TableExpr) error {
 		}
 	default:
 		{
-			return EXPECTATION_FAILED.Extend("invalid from clause")
+			return EXPECTATION_FAILED.Describe("invalid from clause")
+		}
+	}
+}
+
+// BuildCrossJoin turns a comma separated FROM list into a chain of joins.
+// Tables with no alias are given their name as alias (see ImplicitAlias).
+// The conditions of the WHERE clause that only refer to the aliases of the
+// tables joined so far become the condition of the join, so rows that do
+// not match are never produced. The remaining conditions are returned as
+// the new WHERE clause.
+func BuildCrossJoin(from sqlparser.TableExprs, where *sqlparser.Where) (sqlparser.TableExpr, *sqlparser.Where, error) {
+	filters := make([]sqlparser.Expr, 0)
+	if where != nil {
+		filters = sqlparser.SplitAndExpression(filters, where.Expr)
+	}
+	tableExpr := ImplicitAlias(from[0])
+	aliases := TableAliases(tableExpr)
+	for _, right := range from[1:] {
+		right = ImplicitAlias(right)
+		rightAliases := TableAliases(right)
+		for alias := range rightAliases {
+			if aliases[alias] {
+				return nil, nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("the table alias %s is used more than once. Tables of the same name need different aliases", alias))
+			}
+			aliases[alias] = true
+		}
+		conditions := make([]sqlparser.Expr, 0)
+		remaining := make([]sqlparser.Expr, 0)
+		for _, filter := range filters {
+			if IsJoinCondition(filter, aliases, rightAliases) {
+				conditions = append(conditions, filter)
+				continue
+			}
+			remaining = append(remaining, filter)
 		}
+		filters = remaining
+		tableExpr = &sqlparser.JoinTableExpr{
+			LeftExpr:  tableExpr,
+			Join:      sqlparser.NormalJoinType,
+			RightExpr: right,
+			Condition: &sqlparser.JoinCondition{
+				On: sqlparser.AndExpressions(conditions...),
+			},
+		}
+	}
+	if len(filters) == 0 {
+		return tableExpr, nil, nil
 	}
+	return tableExpr, &sqlparser.Where{
+		Type: sqlparser.WhereClause,
+		Expr: sqlparser.AndExpressions(filters...),
+	}, nil
 }
 
+// ImplicitAlias gives a joined table, of a JOIN or of a comma separated
+// FROM list, that has no alias the last part of its name as alias, so that
+// `root.a` is qualified as a. Without it, the keys of the rows of every such
+// table would be merged into one row and the columns of one would hide the
+// other.
+func ImplicitAlias(tableExpr sqlparser.TableExpr) sqlparser.TableExpr {
+	aliasedTableExpr, ok := tableExpr.(*sqlparser.AliasedTableExpr)
+	if !ok || !aliasedTableExpr.As.IsEmpty() {
+		return tableExpr
+	}
+	tableName, ok := aliasedTableExpr.Expr.(sqlparser.TableName)
+	if !ok {
+		return tableExpr
+	}
+	name := tableName.Name.String()
+	aliased := *aliasedTableExpr
+	aliased.As = sqlparser.NewIdentifierCS(name[strings.LastIndex(name, ".")+1:])
+	return &aliased
+}
+
+// TableAliases returns the aliases rows of a table expression are nested under
+func TableAliases(tableExpr sqlparser.TableExpr) map[string]bool {
+	aliases := make(map[string]bool)
+	switch tableExpr := tableExpr.(type) {
+	case *sqlparser.AliasedTableExpr:
+		{
+			if !tableExpr.As.IsEmpty() {
+				aliases[tableExpr.As.String()] = true
+			}
+		}
+	case *sqlparser.JoinTableExpr:
+		{
+			for alias := range TableAliases(ImplicitAlias(tableExpr.LeftExpr)) {
+				aliases[alias] = true
+			}
+			for alias := range TableAliases(ImplicitAlias(tableExpr.RightExpr)) {
+				aliases[alias] = true
+			}
+		}
+	case *sqlparser.ParenTableExpr:
+		{
+			for _, tableExpr := range tableExpr.Exprs {
+				for alias := range TableAliases(tableExpr) {
+					aliases[alias] = true
+				}
+			}
+		}
+	}
+	return aliases
+}
+
+// IsJoinCondition reports whether a condition can be evaluated while joining
+// a table. Every column it reads must be qualified with one of the aliases
+// joined so far, at least one of which belongs to the table being joined.
+// Conditions with subqueries are left to the WHERE clause.
+func IsJoinCondition(expr sqlparser.Expr, aliases map[string]bool, rightAliases map[string]bool) bool {
+	isCondition := true
+	isRight := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.ColName:
+			{
+				if IsParameter(node) {
+					return true, nil
+				}
+				qualifier := node.Qualifier
+				if !qualifier.Qualifier.IsEmpty() || !aliases[qualifier.Name.String()] {
+					isCondition = false
+					return false, nil
+				}
+				if rightAliases[qualifier.Name.String()] {
+					isRight = true
+				}
+			}
+		case *sqlparser.Subquery, *sqlparser.ExistsExpr:
+			{
+				isCondition = false
+				return false, nil
+			}
+		}
+		return true, nil
+	}, expr)
+	return isCondition && isRight
+}
+
+// FullOuterJoinType is the join type of FULL [OUTER] JOIN, which the parser
+// does not know. RewriteJoins carries a full join as a LEFT JOIN whose
+// condition is wrapped in a function and JoinDefinition reads it back.
+const FullOuterJoinType = sqlparser.NaturalRightJoinType + 1
+
+// JoinName returns the SQL name of a join type
+func JoinName(joinType sqlparser.JoinType) string {
+	if joinType == FullOuterJoinType {
+		return "full outer join"
+	}
+	return joinType.ToString()
+}
+
+// JoinDefinition returns the type and the condition of a join. A full join
+// is unwrapped (see RewriteJoins) and a STRAIGHT_JOIN, which in MySQL is an
+// inner join that keeps the order of its tables, is an inner join.
+func JoinDefinition(joinExpr *sqlparser.JoinTableExpr) (sqlparser.JoinType, sqlparser.Expr) {
+	var on sqlparser.Expr
+	if joinExpr.Condition != nil {
+		on = joinExpr.Condition.On
+	}
+	switch joinExpr.Join {
+	case sqlparser.StraightJoinType:
+		{
+			return sqlparser.NormalJoinType, on
+		}
+	case sqlparser.LeftJoinType:
+		{
+			if condition, ok := FullJoinCondition(on); ok {
+				return FullOuterJoinType, condition
+			}
+		}
+	}
+	return joinExpr.Join, on
+}
+
+// FullJoinCondition unwraps the condition of a full join (see RewriteJoins)
+func FullJoinCondition(expr sqlparser.Expr) (sqlparser.Expr, bool) {
+	funcExpr, ok := expr.(*sqlparser.FuncExpr)
+	if !ok || funcExpr.Name.Lowered() != FullJoin || !funcExpr.Qualifier.IsEmpty() || len(funcExpr.Exprs) != 1 {
+		return expr, false
+	}
+	aliasedExpr, ok := funcExpr.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return expr, false
+	}
+	return aliasedExpr.Expr, true
+}
+
+// BuildJoin executes a join. Tables with no alias are given their name as
+// alias (see ImplicitAlias), so their columns can be qualified with it.
 func BuildJoin(query *Query, joinExpr *sqlparser.JoinTableExpr) error {
+	leftExpr := ImplicitAlias(joinExpr.LeftExpr)
+	rightExpr := ImplicitAlias(joinExpr.RightExpr)
+	rightAliases := TableAliases(rightExpr)
+	for alias := range TableAliases(leftExpr) {
+		if rightAliases[alias] {
+			return EXPECTATION_FAILED.Describe(fmt.Sprintf("the table alias %s is used more than once. Tables of the same name need different aliases", alias))
+		}
+	}
 	left := CopyQuery(query)
-	err := BuildFrom(left, &joinExpr.LeftExpr)
+	err := BuildFrom(left, &leftExpr)
 	if err != nil {
 		return err
 	}
+	joinType, on := JoinDefinition(joinExpr)
+	if tableExpr, ok := IsLateral(joinExpr.RightExpr); ok {
+		rs, err := ExecLateralJoin(query, left.from, tableExpr, on, joinType)
+		if err != nil {
+			return Annotate(err, "JOIN", on)
+		}
+		query.from = rs
+		return nil
+	}
 	right := CopyQuery(query)
-	err = BuildFrom(right, &joinExpr.RightExpr)
+	err = BuildFrom(right, &rightExpr)
 	if err != nil {
 		return err
 	}
-	rs, err := ExecJoin(query, left.from, right.from, joinExpr.Condition.On, joinExpr.Join)
+	rs, err := ExecJoin(query, left.from, right.from, on, joinType)
 	if err != nil {
-		return nil
+		return Annotate(err, "JOIN", on)
 	}
 	query.from = rs
 	return nil
 }
 
+// IsLateral reports whether a table expression is a LATERAL derived table
+func IsLateral(tableExpr sqlparser.TableExpr) (*sqlparser.AliasedTableExpr, bool) {
+	aliasedTableExpr, ok := tableExpr.(*sqlparser.AliasedTableExpr)
+	if !ok {
+		return nil, false
+	}
+	derivedTable, ok := aliasedTableExpr.Expr.(*sqlparser.DerivedTable)
+	if !ok || !derivedTable.Lateral {
+		return nil, false
+	}
+	return aliasedTableExpr, true
+}
+
+// ExecLateralJoin joins every row on the left with the rows the LATERAL
+// derived table produces for it. The derived table is evaluated with the
+// row as its scope, so it can select from the nested arrays of the row.
+func ExecLateralJoin(query *Query, left []any, tableExpr *sqlparser.AliasedTableExpr, joinExpr sqlparser.Expr, joinType sqlparser.JoinType) ([]any, error) {
+	if joinType != sqlparser.NormalJoinType && joinType != sqlparser.LeftJoinType {
+		return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("failed to build `JOIN` expression. LATERAL cannot be used with %s", strings.ToUpper(JoinName(joinType))))
+	}
+	derivedTable := tableExpr.Expr.(*sqlparser.DerivedTable)
+	alias := tableExpr.As.String()
+	slice := make([]any, 0)
+	for index, row := range left {
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
+		item, ok := row.(Map)
+		if !ok {
+			return nil, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `JOIN` expression, expected object but found %T", row))
+		}
+		subquery, err := query.subquery(Scope(query, item), derivedTable.Select)
+		if err != nil {
+			return nil, AnnotateRow(err, index)
+		}
+		data, err := subquery.execAndPostProcess()
+		if err != nil {
+			return nil, AnnotateRow(err, index)
+		}
+		array, err := AsArray(data)
+		if err != nil {
+			return nil, err
+		}
+		err = CheckInputRows(query, len(array))
+		if err != nil {
+			return nil, err
+		}
+		rs, err := ExecJoin(query, []any{item}, ProcessAlias(array, alias), joinExpr, joinType)
+		if err != nil {
+			return nil, AnnotateRow(err, index)
+		}
+		// Rows with no match have nothing to take the keys of the right
+		// side from, other than the alias
+		for _, item := range rs {
+			item := item.(Map)
+			if _, ok := item[alias]; !ok && len(alias) != 0 {
+				item[alias] = nil
+			}
+		}
+		slice = append(slice, rs...)
+		if err := CheckJoinRows(query, len(slice)); err != nil {
+			return nil, err
+		}
+	}
+	return slice, nil
+}
+
+// ExecJoin joins two tables. Equalities between the two sides in the join
+// condition narrow the rows each row is compared with through a hash table,
+// otherwise every pair of rows is compared. Rows of an outer join that match
+// nothing are filled with nil for every key found on the other side.
 func ExecJoin(query *Query, left []any, right []any, joinExpr sqlparser.Expr, joinType sqlparser.JoinType) ([]any, error) {
 	if joinType == sqlparser.RightJoinType {
 		left, right = right, left
 	}
+	leftKeys, err := JoinKeys(left)
+	if err != nil {
+		return nil, err
+	}
+	rightKeys, err := JoinKeys(right)
+	if err != nil {
+		return nil, err
+	}
+	equalities := JoinEqualities(joinExpr, KeySet(leftKeys), KeySet(rightKeys))
+	candidates, hashed, err := JoinCandidates(query, left, right, equalities)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]int, len(right))
+	for i := range right {
+		all[i] = i
+	}
+	matched := make([]bool, len(right))
 	slice := make([]any, 0)
-	for _, left := range left {
-		left, ok := left.(Map)
-		if !ok {
-			return nil, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `JOIN` expression, expected object but found %T", left))
+	for index, left := range left {
+		if err := query.contextErr(); err != nil {
+			return nil, err
 		}
+		left := left.(Map)
 		joined := false
-		for _, right := range right {
+		indexes := all
+		if hashed {
+			indexes = candidates[index]
+		}
+		for _, i := range indexes {
 			current := make(Map)
 			for key, value := range left {
 				current[key] = value
 			}
-			right, ok := right.(Map)
-			if !ok {
-				return nil, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `JOIN` expression, expected object but found %T", left))
-			}
-			for key, value := range right {
+			for key, value := range right[i].(Map) {
 				current[key] = value
 			}
-			rs, err := Expr(query, current, joinExpr, nil)
-			if err != nil {
-				return nil, err
-			}
-			rsValue, ok := rs.(bool)
-			if !ok {
-				return nil, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `JOIN` expression, expected boolean but found %T", left))
+			rsValue := true
+			// Joins without a condition produce the cartesian product
+			if joinExpr != nil {
+				rs, err := Expr(query, current, joinExpr, nil)
+				if err != nil {
+					return nil, AnnotateRow(err, index)
+				}
+				value, err := IsMatch(query, current, rs, "JOIN")
+				if err != nil {
+					return nil, AnnotateRow(err, index)
+				}
+				rsValue = value
 			}
 			if rsValue {
 				slice = append(slice, current)
 				joined = true
+				matched[i] = true
+				if err := CheckJoinRows(query, len(slice)); err != nil {
+					return nil, err
+				}
 			}
 		}
-		if !joined {
-			current := make(Map)
-			for key, value := range left {
-				current[key] = value
+		if !joined && joinType != sqlparser.NormalJoinType {
+			slice = append(slice, NullFill(left, rightKeys))
+			if err := CheckJoinRows(query, len(slice)); err != nil {
+				return nil, err
 			}
-			if joinType != sqlparser.NormalJoinType {
-				slice = append(slice, current)
+		}
+	}
+	if joinType == FullOuterJoinType {
+		for i, right := range right {
+			if matched[i] {
+				continue
+			}
+			slice = append(slice, NullFill(right.(Map), leftKeys))
+			if err := CheckJoinRows(query, len(slice)); err != nil {
+				return nil, err
 			}
 		}
 	}
 	return slice, nil
 }
 
+// JoinKeys returns every key found in the rows of a joined table
+func JoinKeys(rows []any) ([]string, error) {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, row := range rows {
+		item, ok := row.(Map)
+		if !ok {
+			return nil, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `JOIN` expression, expected object but found %T", row))
+		}
+		for key := range item {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+func KeySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+// NullFill copies a row that matched nothing in an outer join and sets the
+// keys of the missing side to nil
+func NullFill(row Map, keys []string) Map {
+	current := make(Map, len(row)+len(keys))
+	for _, key := range keys {
+		current[key] = nil
+	}
+	for key, value := range row {
+		current[key] = value
+	}
+	return current
+}
+
 func BuildLiteral(expr sqlparser.Expr) (sqlparser.ValType, string, error) {
 	literal, ok := expr.(*sqlparser.Literal)
 	if !ok {
-		return 0, "", INVALID_TYPE.Extend(fmt.Sprintf("failed to build `LITERAL` expression, expected Literal but found %T", expr))
+		return 0, "", INVALID_TYPE.Describe(fmt.Sprintf("failed to build `LITERAL` expression, expected Literal but found %T", expr))
 	}
 	return literal.Type, literal.Val, nil
 }
@@ -484,7 +1237,7 @@ func BuildLiteral(expr sqlparser.Expr) (sqlparser.ValType, string, error) {
 func BuildColumnName(expr sqlparser.Expr) (string, string, error) {
 	columnName, ok := expr.(*sqlparser.ColName)
 	if !ok {
-		return "", "", INVALID_TYPE.Extend(fmt.Sprintf("failed to build `COLUMN` name. expected ColName but found %T", expr))
+		return "", "", INVALID_TYPE.Describe(fmt.Sprintf("failed to build `COLUMN` name. expected ColName but found %T", expr))
 	}
 	return columnName.Qualifier.Name.String(), columnName.Name.String(), nil
 }
@@ -501,9 +1254,9 @@ func BuilFromAliasedTable(query *Query, as string, expr sqlparser.SimpleTableExp
 			} else {
 				tableName = fmt.Sprintf("%s.%s", qualifier, name)
 			}
-			data, err := ExecReader(query.data, tableName)
+			data, err := query.Registry().ExecReader(query.data, tableName)
 			if err != nil {
-				return err
+				return Annotate(err, "FROM", nil)
 			}
 			switch data := data.(type) {
 			case CteEvaluation:
@@ -516,6 +1269,10 @@ func BuilFromAliasedTable(query *Query, as string, expr sqlparser.SimpleTableExp
 					if err != nil {
 						return err
 					}
+					err = CheckInputRows(query, len(array))
+					if err != nil {
+						return err
+					}
 					alias := ProcessAlias(array, as)
 					query.from = alias
 					return nil
@@ -539,6 +1296,10 @@ func BuilFromAliasedTable(query *Query, as string, expr sqlparser.SimpleTableExp
 					if err != nil {
 						return err
 					}
+					err = CheckInputRows(query, len(array))
+					if err != nil {
+						return err
+					}
 					alias := ProcessAlias(array, as)
 					query.from = alias
 					return nil
@@ -547,7 +1308,7 @@ func BuilFromAliasedTable(query *Query, as string, expr sqlparser.SimpleTableExp
 		}
 	case *sqlparser.DerivedTable:
 		{
-			subquery, err := Prepare(query.data, expr.Select, query.options)
+			subquery, err := query.subquery(query.data, expr.Select)
 			if err != nil {
 				return err
 			}
@@ -565,13 +1326,17 @@ func BuilFromAliasedTable(query *Query, as string, expr sqlparser.SimpleTableExp
 			if err != nil {
 				return err
 			}
+			err = CheckInputRows(query, len(array))
+			if err != nil {
+				return err
+			}
 			alias := ProcessAlias(array, as)
 			query.from = alias
 			return nil
 		}
 	default:
 		{
-			return UNSUPPORTED_CASE.Extend("invalid from clause")
+			return UNSUPPORTED_CASE.Describe("invalid from clause")
 		}
 	}
 }
@@ -590,6 +1355,11 @@ func ProcessAlias(data []any, as string) []any {
 }
 
 func Expr(query *Query, current Map, expr sqlparser.Expr, options *ExpressionReaderOptions) (any, error) {
+	if key, ok := GroupLookup(query, expr); ok {
+		if _, ok := current["*"]; ok {
+			return current[key], nil
+		}
+	}
 	switch expr := expr.(type) {
 	case *sqlparser.AndExpr:
 		{
@@ -643,8 +1413,15 @@ func Expr(query *Query, current Map, expr sqlparser.Expr, options *ExpressionRea
 		{
 			return bool(expr), nil
 		}
+	case sqlparser.Argument:
+		{
+			return ParamExpr(query, expr)
+		}
 	case *sqlparser.ColName:
 		{
+			if IsParameter(expr) {
+				return ParamExpr(query, expr)
+			}
 			qualifier, name, err := BuildColumnName(expr)
 			if err != nil {
 				return nil, err
@@ -675,6 +1452,10 @@ func Expr(query *Query, current Map, expr sqlparser.Expr, options *ExpressionRea
 		{
 			return AggrFunExpr(query, current, expr)
 		}
+	case *sqlparser.ArgumentLessWindowExpr, *sqlparser.FirstOrLastValueExpr, *sqlparser.NtileExpr, *sqlparser.NTHValueExpr, *sqlparser.LagLeadExpr:
+		{
+			return WindowExpr(query, current, expr)
+		}
 	default:
 		{
 			return nil, UNSUPPORTED_CASE
@@ -682,77 +1463,95 @@ func Expr(query *Query, current Map, expr sqlparser.Expr, options *ExpressionRea
 	}
 }
 
-func AndExpr(query *Query, current Map, expr *sqlparser.AndExpr) (bool, error) {
-	left, err := Expr(query, current, expr.Left, nil)
+// AndExpr follows the three-valued logic of SQL: FALSE AND NULL is FALSE,
+// TRUE AND NULL is NULL. NULL stands for UNKNOWN and is returned as nil.
+// The right side is not evaluated when the left side is FALSE.
+func AndExpr(query *Query, current Map, expr *sqlparser.AndExpr) (any, error) {
+	left, err := Condition(query, current, expr.Left, "AND")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	leftValueRaw, err := ValueOf(query, current, left)
+	if left != nil && !*left {
+		return false, nil
+	}
+	right, err := Condition(query, current, expr.Right, "AND")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if leftValueRaw == nil {
-		return false, EXPECTATION_FAILED.Extend("failed to build `AND` expreesion. left side value is nil")
+	if right != nil && !*right {
+		return false, nil
 	}
-	leftValue, err := AsType[bool](leftValueRaw)
-	if err != nil {
-		return false, err
+	if left == nil || right == nil {
+		return nil, nil
 	}
-	right, err := Expr(query, current, expr.Right, nil)
+	return true, nil
+}
+
+// OrExpr follows the three-valued logic of SQL: TRUE OR NULL is TRUE,
+// FALSE OR NULL is NULL. The right side is not evaluated when the left side
+// is TRUE.
+func OrExpr(query *Query, current Map, expr *sqlparser.OrExpr) (any, error) {
+	left, err := Condition(query, current, expr.Left, "OR")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	rightValueRaw, err := ValueOf(query, current, right)
+	if left != nil && *left {
+		return true, nil
+	}
+	right, err := Condition(query, current, expr.Right, "OR")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if rightValueRaw == nil {
-		return false, EXPECTATION_FAILED.Extend("failed to build `AND` expreesion. right side value is nil")
+	if right != nil && *right {
+		return true, nil
 	}
-	rightValue, err := AsType[bool](rightValueRaw)
-	if err != nil {
-		return false, err
+	if left == nil || right == nil {
+		return nil, nil
 	}
-	return *leftValue && *rightValue, nil
+	return false, nil
 }
 
-func OrExpr(query *Query, current Map, expr *sqlparser.OrExpr) (bool, error) {
-	left, err := Expr(query, current, expr.Left, nil)
-	if err != nil {
-		return false, err
-	}
-	leftValueRaw, err := ValueOf(query, current, left)
+// Condition evaluates an operand of a boolean operator. NULL is returned as
+// nil, or fails the expression when nulls are strict.
+func Condition(query *Query, current Map, expr sqlparser.Expr, operator string) (*bool, error) {
+	rs, err := Expr(query, current, expr, nil)
 	if err != nil {
-		return false, err
-	}
-	if leftValueRaw == nil {
-		return false, EXPECTATION_FAILED.Extend("failed to build `OR` expreesion. left side value is nil")
+		return nil, err
 	}
-	leftValue, err := AsType[bool](leftValueRaw)
+	value, err := ValueOf(query, current, rs)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	right, err := Expr(query, current, expr.Right, nil)
-	if err != nil {
-		return false, err
+	if value == nil {
+		if query.options.strictNulls {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `%s` expreesion. %s is nil", operator, sqlparser.String(expr)))
+		}
+		return nil, nil
 	}
-	rightValueRaw, err := ValueOf(query, current, right)
+	return AsType[bool](value)
+}
+
+// IsMatch reports whether the value of a condition is TRUE. Rows for which
+// a condition is UNKNOWN do not match, unless nulls are strict, in which
+// case the condition fails.
+func IsMatch(query *Query, current Map, rs any, clause string) (bool, error) {
+	value, err := ValueOf(query, current, rs)
 	if err != nil {
 		return false, err
 	}
-	if rightValueRaw == nil {
-		return false, EXPECTATION_FAILED.Extend("failed to build `OR` expreesion. right side value is nil")
+	if value == nil && !query.options.strictNulls {
+		return false, nil
 	}
-	rightValue, err := AsType[bool](rightValueRaw)
-	if err != nil {
-		return false, err
+	result, ok := value.(bool)
+	if !ok {
+		return false, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `%s` expression. expected a boolean but found %T", clause, value))
 	}
-	return *leftValue || *rightValue, nil
+	return result, nil
 }
 
-func ComparisonExpr(query *Query, current Map, expr *sqlparser.ComparisonExpr) (bool, error) {
-	current["<-"] = query.data
-	defer delete(current, "<-")
+// ComparisonExpr compares two values. Comparing NULL with anything is
+// UNKNOWN, unless nulls are strict.
+func ComparisonExpr(query *Query, current Map, expr *sqlparser.ComparisonExpr) (any, error) {
 	left, err := Expr(query, current, expr.Left, nil)
 	if err != nil {
 		return false, err
@@ -761,6 +1560,23 @@ func ComparisonExpr(query *Query, current Map, expr *sqlparser.ComparisonExpr) (
 	if err != nil {
 		return false, err
 	}
+	if operand, all, ok := Quantifier(expr.Right); ok {
+		return QuantifiedComparison(query, current, expr.Operator, leftValue, operand, all)
+	}
+	switch expr.Operator {
+	case sqlparser.InOp:
+		{
+			return QuantifiedComparison(query, current, sqlparser.EqualOp, leftValue, expr.Right, false)
+		}
+	case sqlparser.NotInOp:
+		{
+			return QuantifiedComparison(query, current, sqlparser.NotEqualOp, leftValue, expr.Right, true)
+		}
+	case sqlparser.LikeOp, sqlparser.NotLikeOp, sqlparser.RegexpOp, sqlparser.NotRegexpOp:
+		{
+			return PatternComparison(query, current, expr, leftValue)
+		}
+	}
 	right, err := Expr(query, current, expr.Right, nil)
 	if err != nil {
 		return false, err
@@ -769,135 +1585,226 @@ func ComparisonExpr(query *Query, current Map, expr *sqlparser.ComparisonExpr) (
 	if err != nil {
 		return false, err
 	}
-
 	switch expr.Operator {
+	case sqlparser.EqualOp, sqlparser.NotEqualOp, sqlparser.GreaterThanOp, sqlparser.GreaterEqualOp, sqlparser.LessThanOp, sqlparser.LessEqualOp:
+		{
+			return CompareOperands(query, expr.Operator, leftValue, rightValue)
+		}
+	default:
+		{
+			return false, UNSUPPORTED_CASE
+		}
+	}
+}
+
+// CompareOperands compares two values with one of =, <>, <, >, <= and >=.
+// Numbers are compared with numbers and numeric strings as float64 (see
+// compare.Values), so the result does not depend on the operand order.
+// Comparing with NULL is UNKNOWN, which is returned as nil, unless strict
+// NULL handling is enabled.
+func CompareOperands(query *Query, operator sqlparser.ComparisonExprOperator, left any, right any) (any, error) {
+	if (left == nil || right == nil) && !query.options.strictNulls {
+		return nil, nil
+	}
+	switch operator {
 	case sqlparser.EqualOp:
 		{
-			return compare.Compare(leftValue, rightValue) == 0, nil
+			return compare.Values(left, right) == 0, nil
 		}
 	case sqlparser.NotEqualOp:
 		{
-			return compare.Compare(leftValue, rightValue) != 0, nil
+			return compare.Values(left, right) != 0, nil
 		}
 	case sqlparser.GreaterThanOp:
 		{
-			return compare.Compare(leftValue, rightValue) == 1, nil
+			return compare.Values(left, right) == 1, nil
 		}
 	case sqlparser.GreaterEqualOp:
 		{
-			return compare.Compare(leftValue, rightValue) >= 0, nil
+			return compare.Values(left, right) >= 0, nil
 		}
 	case sqlparser.LessThanOp:
 		{
-			return compare.Compare(leftValue, rightValue) == -1, nil
+			return compare.Values(left, right) == -1, nil
+		}
+	case sqlparser.LessEqualOp:
+		{
+			return compare.Values(left, right) <= 0, nil
+		}
+	default:
+		{
+			return false, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%s cannot be used with ANY or ALL", operator.ToString()))
+		}
+	}
+}
+
+// QuantifiedComparison compares a value with every candidate of a list, a
+// subquery or an array. With ANY it is TRUE when a comparison is TRUE, with
+// ALL it is FALSE when a comparison is FALSE. Otherwise an UNKNOWN
+// comparison makes the result UNKNOWN, so 1 NOT IN (2, NULL), which is
+// 1 <> ALL (2, NULL), is not TRUE. IN is = ANY and NOT IN is <> ALL.
+func QuantifiedComparison(query *Query, current Map, operator sqlparser.ComparisonExprOperator, left any, expr sqlparser.Expr, all bool) (any, error) {
+	rs, err := Expr(query, current, expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	value, err := ValueOf(query, current, rs)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		if query.options.strictNulls {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `%s` expression. right side value is nil", operator.ToString()))
 		}
-	case sqlparser.LessEqualOp:
-		{
-			return compare.Compare(leftValue, rightValue) <= 0, nil
+		return nil, nil
+	}
+	candidates, err := Candidates(value)
+	if err != nil {
+		return nil, err
+	}
+	unknown := false
+	for _, candidate := range candidates {
+		match, err := CompareOperands(query, operator, left, candidate)
+		if err != nil {
+			return nil, err
 		}
-	case sqlparser.LikeOp:
-		{
-			return RegexComparison(fmt.Sprintf("%v", leftValue), fmt.Sprintf("%v", rightValue))
+		if match == nil {
+			unknown = true
+			continue
 		}
-	case sqlparser.NotLikeOp:
-		{
-			rs, err := RegexComparison(fmt.Sprintf("%v", leftValue), fmt.Sprintf("%v", rightValue))
-			if err != nil {
-				return false, err
-			}
-			return !rs, nil
+		if match.(bool) != all {
+			return !all, nil
 		}
-	case sqlparser.InOp:
-		{
-			if right == nil {
-				return false, EXPECTATION_FAILED.Extend("failed to build `IN` expreesion. right side value is nil")
-			}
-			rightArray, ok := (right).([]any)
-			if !ok {
-				return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `IN` expression. expected an array but found %T", right))
-			}
-			for _, value := range rightArray {
-				switch value := value.(type) {
-				case Map:
-					{
-						for _, value := range value {
-							if v, ok := value.(*float64); ok {
-								value = *v
-							}
-							if compare.Compare(leftValue, value) == 0 {
-								return true, nil
-							}
-							break
-						}
-					}
-				default:
-					{
-						if v, ok := value.(*float64); ok {
-							value = *v
-						}
-						if compare.Compare(leftValue, value) == 0 {
-							return true, nil
-						}
+	}
+	if unknown {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// Candidates lists the values the right side of IN, ANY or ALL stands for.
+// Arrays, including array columns in a value list, are expanded and the
+// rows of a subquery, including the single row of a subquery without FROM,
+// are replaced with the value of their only column.
+func Candidates(value any) ([]any, error) {
+	if row, ok := value.(Map); ok {
+		value = []any{row}
+	}
+	array, ok := value.([]any)
+	if !ok {
+		return []any{value}, nil
+	}
+	candidates := make([]any, 0, len(array))
+	for _, item := range array {
+		switch item := item.(type) {
+		case Map:
+			{
+				if len(item) != 1 {
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("expected the subquery to select one column but found %d", len(item)))
+				}
+				for _, value := range item {
+					if v, ok := value.(*float64); ok {
+						value = *v
 					}
+					candidates = append(candidates, value)
 				}
 			}
-			return false, nil
-		}
-	case sqlparser.NotInOp:
-		{
-			if right == nil {
-				return false, EXPECTATION_FAILED.Extend("failed to build `NOT IN` expreesion. right side value is nil")
+		case []any:
+			{
+				candidates = append(candidates, item...)
 			}
-			rightArray, ok := (right).([]any)
-			if !ok {
-				return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `IN` expression. expected an array but found %T", right))
+		case *float64:
+			{
+				candidates = append(candidates, *item)
 			}
-			for _, value := range rightArray {
-				if leftValue == fmt.Sprintf("%v", value) {
-					return false, nil
-				}
+		default:
+			{
+				candidates = append(candidates, item)
 			}
-			return true, nil
-		}
-	default:
-		{
-			return false, UNSUPPORTED_CASE
 		}
 	}
+	return candidates, nil
 }
 
-func BetweenExpr(query *Query, current Map, expr *sqlparser.BetweenExpr) (bool, error) {
-	point, err := Expr(query, current, expr.Left, nil)
-	if err != nil {
-		return false, err
-	}
-	pointValueRaw, err := ValueOf(query, current, point)
-	if err != nil {
-		return false, err
+// Quantifier unwraps the right side of a comparison with ANY or ALL (see
+// RewriteQuantifiers)
+func Quantifier(expr sqlparser.Expr) (sqlparser.Expr, bool, bool) {
+	funcExpr, ok := expr.(*sqlparser.FuncExpr)
+	if !ok || !funcExpr.Qualifier.IsEmpty() || len(funcExpr.Exprs) != 1 {
+		return expr, false, false
 	}
-	// TO DO: could be either a number or a date
-	from, err := Expr(query, current, expr.From, nil)
-	if err != nil {
-		return false, err
+	name := funcExpr.Name.Lowered()
+	if name != AnyQuantifier && name != AllQuantifier {
+		return expr, false, false
 	}
-	// TO DO: could be either a number or a date
-	to, err := Expr(query, current, expr.To, nil)
-	if err != nil {
-		return false, err
+	aliasedExpr, ok := funcExpr.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return expr, false, false
 	}
-	pointValue := fmt.Sprintf("%v", pointValueRaw)
-	fromValue := fmt.Sprintf("%v", from)
-	toValue := fmt.Sprintf("%v", to)
-	switch expr.IsBetween {
-	case true:
-		{
-			return (pointValue > fromValue) && (pointValue < toValue), nil
+	return aliasedExpr.Expr, name == AllQuantifier, true
+}
+
+// BetweenExpr is inclusive: x BETWEEN a AND b is a <= x AND x <= b. When
+// an operand is a number the operands are compared as numbers (see
+// compare.Values) and when every operand is an ISO-8601 timestamp they are
+// compared as points in time. SYMMETRIC swaps the bounds when they are out of order. A NULL
+// operand makes the result UNKNOWN.
+func BetweenExpr(query *Query, current Map, expr *sqlparser.BetweenExpr) (any, error) {
+	from, symmetric := SymmetricBound(expr.From)
+	values := make([]any, 0, 3)
+	for _, operand := range []sqlparser.Expr{expr.Left, from, expr.To} {
+		rs, err := Expr(query, current, operand, nil)
+		if err != nil {
+			return nil, err
 		}
-	default:
-		{
-			return !((pointValue > fromValue) && (pointValue < toValue)), nil
+		value, err := ValueOf(query, current, rs)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil && !query.options.strictNulls {
+			return nil, nil
+		}
+		values = append(values, value)
+	}
+	values = Temporal(values)
+	point, lower, upper := values[0], values[1], values[2]
+	if symmetric && compare.Values(lower, upper) > 0 {
+		lower, upper = upper, lower
+	}
+	rs := compare.Values(point, lower) >= 0 && compare.Values(point, upper) <= 0
+	if !expr.IsBetween {
+		return !rs, nil
+	}
+	return rs, nil
+}
+
+// SymmetricBound unwraps the lower bound of a BETWEEN SYMMETRIC expression
+// (see RewriteBetween)
+func SymmetricBound(expr sqlparser.Expr) (sqlparser.Expr, bool) {
+	funcExpr, ok := expr.(*sqlparser.FuncExpr)
+	if !ok || funcExpr.Name.Lowered() != SymmetricBetween || !funcExpr.Qualifier.IsEmpty() || len(funcExpr.Exprs) != 1 {
+		return expr, false
+	}
+	aliasedExpr, ok := funcExpr.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return expr, false
+	}
+	return aliasedExpr.Expr, true
+}
+
+// Temporal converts the values to time.Time when all of them are
+// timestamps, so that timestamps written with different offsets or
+// precisions compare as points in time rather than as text
+func Temporal(values []any) []any {
+	timestamps := make([]any, 0, len(values))
+	for _, value := range values {
+		timestamp, ok := compare.Timestamp(value)
+		if !ok {
+			return values
 		}
+		timestamps = append(timestamps, timestamp)
 	}
+	return timestamps
 }
 
 func BinaryExpr(query *Query, current Map, expr *sqlparser.BinaryExpr) (*float64, error) {
@@ -912,7 +1819,7 @@ func BinaryExpr(query *Query, current Map, expr *sqlparser.BinaryExpr) (*float64
 	if leftValueRaw == nil {
 		return nil, nil
 	}
-	leftValue, err := AsType[float64](leftValueRaw)
+	leftValue, err := AsNumber(leftValueRaw)
 	if err != nil {
 		return nil, err
 	}
@@ -927,7 +1834,7 @@ func BinaryExpr(query *Query, current Map, expr *sqlparser.BinaryExpr) (*float64
 	if rightValueRaw == nil {
 		return nil, nil
 	}
-	rightValue, err := AsType[float64](rightValueRaw)
+	rightValue, err := AsNumber(rightValueRaw)
 	if err != nil {
 		return nil, err
 	}
@@ -1040,22 +1947,22 @@ func IsExpr(query *Query, current Map, expr *sqlparser.IsExpr) (bool, error) {
 	case sqlparser.IsTrueOp, sqlparser.IsNotFalseOp:
 		{
 			if leftValue == nil {
-				return false, EXPECTATION_FAILED.Extend("failed to build `IS` expreesion. left side value is nil")
+				return false, EXPECTATION_FAILED.Describe("failed to build `IS` expreesion. left side value is nil")
 			}
 			leftValue, ok := (leftValue).(bool)
 			if !ok {
-				return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `IN` expression. expected a boolean but found %T", left))
+				return false, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `IN` expression. expected a boolean but found %T", left))
 			}
 			return leftValue, nil
 		}
 	case sqlparser.IsNotTrueOp, sqlparser.IsFalseOp:
 		{
 			if leftValue == nil {
-				return false, EXPECTATION_FAILED.Extend("failed to build `IS` expreesion. left side value is nil")
+				return false, EXPECTATION_FAILED.Describe("failed to build `IS` expreesion. left side value is nil")
 			}
 			leftValue, ok := (leftValue).(bool)
 			if !ok {
-				return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `IN` expression. expected a boolean but found %T", left))
+				return false, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `IN` expression. expected a boolean but found %T", left))
 			}
 			return !leftValue, nil
 		}
@@ -1066,23 +1973,16 @@ func IsExpr(query *Query, current Map, expr *sqlparser.IsExpr) (bool, error) {
 	}
 }
 
-func NotExpr(query *Query, current Map, expr *sqlparser.NotExpr) (bool, error) {
-	rs, err := Expr(query, current, expr.Expr, nil)
-	if err != nil {
-		return false, err
-	}
-	rsValueRaw, err := ValueOf(query, current, rs)
+// NotExpr returns NULL for NULL
+func NotExpr(query *Query, current Map, expr *sqlparser.NotExpr) (any, error) {
+	value, err := Condition(query, current, expr.Expr, "NOT")
 	if err != nil {
-		return false, err
-	}
-	if rsValueRaw == nil {
-		return false, EXPECTATION_FAILED.Extend("failed to build `NOT` expreesion. left side value is nil")
+		return nil, err
 	}
-	rsValue, err := AsType[bool](rsValueRaw)
-	if err != nil {
-		return false, err
+	if value == nil {
+		return nil, nil
 	}
-	return !*rsValue, nil
+	return !*value, nil
 }
 
 func SubStrExpr(query *Query, current Map, expr *sqlparser.SubstrExpr) (string, error) {
@@ -1095,7 +1995,7 @@ func SubStrExpr(query *Query, current Map, expr *sqlparser.SubstrExpr) (string,
 		return "", err
 	}
 	if strValueRaw == nil {
-		return "", EXPECTATION_FAILED.Extend("failed to build `SubStr` expreesion. the given value is nil")
+		return "", EXPECTATION_FAILED.Describe("failed to build `SubStr` expreesion. the given value is nil")
 	}
 	strValue, err := AsType[string](strValueRaw)
 	if err != nil {
@@ -1106,15 +2006,15 @@ func SubStrExpr(query *Query, current Map, expr *sqlparser.SubstrExpr) (string,
 		return "", err
 	}
 	if from == nil {
-		return "", EXPECTATION_FAILED.Extend("failed to build `IS` expreesion. the `from` argument is nil")
+		return "", EXPECTATION_FAILED.Describe("failed to build `IS` expreesion. the `from` argument is nil")
 	}
 	if colName, ok := from.(ColumnName); ok {
-		from, err = ExecReader(current, string(colName))
+		from, err = query.Registry().ExecReader(current, string(colName))
 		if err != nil {
 			return "", err
 		}
 	}
-	fromValue, err := AsType[float64](from)
+	fromValue, err := AsNumber(from)
 	if err != nil {
 		return "", err
 	}
@@ -1123,15 +2023,15 @@ func SubStrExpr(query *Query, current Map, expr *sqlparser.SubstrExpr) (string,
 		return "", err
 	}
 	if to == nil {
-		return "", EXPECTATION_FAILED.Extend("failed to build `IS` expreesion. the `to` argument is nil")
+		return "", EXPECTATION_FAILED.Describe("failed to build `IS` expreesion. the `to` argument is nil")
 	}
 	if colName, ok := to.(ColumnName); ok {
-		to, err = ExecReader(current, string(colName))
+		to, err = query.Registry().ExecReader(current, string(colName))
 		if err != nil {
 			return "", err
 		}
 	}
-	toValue, err := AsType[float64](to)
+	toValue, err := AsNumber(to)
 	if err != nil {
 		return "", err
 	}
@@ -1148,12 +2048,12 @@ func UnaryExpr(query *Query, current Map, expr *sqlparser.UnaryExpr) (any, error
 		return nil, err
 	}
 	if valRawValue == nil {
-		return nil, EXPECTATION_FAILED.Extend("failed to build `UNARY` expreesion. the given value is nil")
+		return nil, EXPECTATION_FAILED.Describe("failed to build `UNARY` expreesion. the given value is nil")
 	}
 	switch expr.Operator {
 	case sqlparser.TildaOp:
 		{
-			valValue, err := AsType[float64](valRawValue)
+			valValue, err := AsNumber(valRawValue)
 			if err != nil {
 				return nil, err
 			}
@@ -1162,7 +2062,7 @@ func UnaryExpr(query *Query, current Map, expr *sqlparser.UnaryExpr) (any, error
 		}
 	case sqlparser.UMinusOp:
 		{
-			valValue, err := AsType[float64](valRawValue)
+			valValue, err := AsNumber(valRawValue)
 			if err != nil {
 				return nil, err
 			}
@@ -1187,19 +2087,17 @@ func UnaryExpr(query *Query, current Map, expr *sqlparser.UnaryExpr) (any, error
 
 func ValueTupleExpr(query *Query, current Map, expr *sqlparser.ValTuple) ([]any, error) {
 	if expr == nil {
-		return nil, EXPECTATION_FAILED.Extend("failed to build `VALUE TUPLE` expreesion. the expression is nil")
+		return nil, EXPECTATION_FAILED.Describe("failed to build `VALUE TUPLE` expreesion. the expression is nil")
 	}
 	slice := make([]any, 0)
 	for _, value := range *expr {
-		value, err := Expr(query, current, value, nil)
+		rs, err := Expr(query, current, value, nil)
 		if err != nil {
 			return nil, err
 		}
-		if colName, ok := value.(ColumnName); ok {
-			value, err = ExecReader(current, string(colName))
-			if err != nil {
-				return nil, err
-			}
+		value, err := ValueOf(query, current, rs)
+		if err != nil {
+			return nil, err
 		}
 		slice = append(slice, value)
 	}
@@ -1213,6 +2111,9 @@ func SelectExpr(query *Query, current Map, expr *sqlparser.SelectExprs) (Map, er
 		case *sqlparser.StarExpr:
 			{
 				for key, value := range current {
+					if key == windowKey {
+						continue
+					}
 					query.postProcessors = append(query.postProcessors, func() error {
 						delete(data, "<-")
 						return nil
@@ -1224,14 +2125,14 @@ func SelectExpr(query *Query, current Map, expr *sqlparser.SelectExprs) (Map, er
 			{
 				value, err := Expr(query, current, expr.Expr, nil)
 				if err != nil {
-					return nil, err
+					return nil, Annotate(err, "SELECT", expr)
 				}
 				if _, ok := value.(Ommit); ok {
 					continue
 				}
 				valueRaw, err := ValueOf(query, current, value)
 				if err != nil {
-					return nil, err
+					return nil, Annotate(err, "SELECT", expr)
 				}
 				// Fuse blends all the keys to the current row
 				// Fuse types only come from the FuseFunc function
@@ -1279,13 +2180,7 @@ func SelectExpr(query *Query, current Map, expr *sqlparser.SelectExprs) (Map, er
 }
 
 func SubqueryExpr(query *Query, current Map, expr *sqlparser.Subquery) (any, error) {
-	// Backward Navigation
-	current["<-"] = query.data
-	query.postProcessors = append(query.postProcessors, func() error {
-		delete(current, "<-")
-		return nil
-	})
-	subQuery, err := Prepare(current, expr.Select, query.options)
+	subQuery, err := query.subquery(Scope(query, current), expr.Select)
 	if err != nil {
 		return nil, err
 	}
@@ -1308,9 +2203,9 @@ func CaseExpr(query *Query, current Map, expr *sqlparser.CaseExpr) (any, error)
 		if err != nil {
 			return nil, err
 		}
-		value, ok := rs.(bool)
-		if !ok {
-			return nil, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `CASE` caluse. expected a boolean but found %T", value))
+		value, err := IsMatch(query, current, rs, "CASE")
+		if err != nil {
+			return nil, err
 		}
 		if value {
 			return Expr(query, current, when.Val, nil)
@@ -1326,30 +2221,33 @@ func CaseExpr(query *Query, current Map, expr *sqlparser.CaseExpr) (any, error)
 // The existing Exist function is inefficient as it does not break when
 // it finds the first value
 func ExistExpr(query *Query, current Map, expr *sqlparser.ExistsExpr) (bool, error) {
-	// Backward Navigation
-	current["<-"] = query.data
-	query.postProcessors = append(query.postProcessors, func() error {
-		delete(current, "<-")
-		return nil
-	})
-	q, err := Prepare(current, expr.Subquery.Select, query.options)
+	scope := Scope(query, current)
+	q, err := query.subquery(scope, expr.Subquery.Select)
 	if err != nil {
 		return false, err
 	}
-	for i := 0; i < len(q.from); i++ {
-		item, ok := q.from[i].(Map)
+	// The rows of the subquery are merged with the current row in copies,
+	// as they may belong to the caller's document
+	from := make([]any, len(q.from))
+	for i, item := range q.from {
+		item, ok := item.(Map)
 		if !ok {
-			return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `EXIST` expression. expected an object but found %T", item))
+			return false, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `EXIST` expression. expected an object but found %T", item))
 		}
-		for key, value := range current {
-			item[key] = value
+		row := make(Map, len(item)+len(scope))
+		for key, value := range item {
+			row[key] = value
 		}
-		q.from[i] = item
+		for key, value := range scope {
+			row[key] = value
+		}
+		from[i] = row
 	}
+	q.from = from
 	rs, err := q.exec()
 	array, ok := rs.([]any)
 	if !ok {
-		return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `EXIST` expression. expected an array but found %T", array))
+		return false, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `EXIST` expression. expected an array but found %T", array))
 	}
 	query.postProcessors = append(query.postProcessors, q.postProcessors...)
 	query.wg.Add(1)
@@ -1378,42 +2276,62 @@ func FunExpr(query *Query, current Map, expr *sqlparser.FuncExpr) (any, error) {
 		return &rs, err
 	}
 
-	function, ok := functions[expr.Name.Lowered()]
+	function, ok := query.Registry().Function(name)
 	if !ok {
-		return nil, INVALID_FUNCTION.Extend(fmt.Sprintf("function %s cannot be found", expr.Name.String()))
+		return nil, INVALID_FUNCTION.Describe(fmt.Sprintf("function %s cannot be found", expr.Name.String()))
+	}
+	functionOptions := &FunctionOptions{
+		Context: query.Context(),
 	}
 	execType := strings.ToLower(expr.Qualifier.String())
-	isimmediate := IsImmediateFunction(name)
+	isimmediate := query.Registry().IsImmediateFunction(name)
+	islazy := query.Registry().IsLazyFunction(name)
 	switch execType {
 	case "async":
 		{
 			if isimmediate {
-				return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("%s is an immediate function and it cannot be run asynchronously", name))
+				return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("%s is an immediate function and it cannot be run asynchronously", name))
 			}
 			slice, e := FuncArgReader(query, current, expr.Exprs)
 			if e != nil {
 				return nil, e
 			}
+			if err := CheckGoroutines(query); err != nil {
+				return nil, err
+			}
 			var rs any
 			var err error
 			query.wg.Add(1)
 			go func() {
-				rs, err = function(query, current, nil, slice)
-				query.wg.Done()
+				defer query.wg.Done()
+				if err = functionOptions.Context.Err(); err != nil {
+					return
+				}
+				rs, err = function(query, current, functionOptions, slice)
 			}()
-			return &rs, err
+			// The error is only read once the function has returned
+			query.postProcessors = append(query.postProcessors, func() error {
+				return err
+			})
+			return &rs, nil
 		}
 	case "spin":
 		{
 			if isimmediate {
-				return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("%s is an immediate function and it cannot be spinned", name))
+				return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("%s is an immediate function and it cannot be spinned", name))
 			}
 			slice, e := FuncArgReader(query, current, expr.Exprs)
 			if e != nil {
 				return nil, e
 			}
+			if err := CheckGoroutines(query); err != nil {
+				return nil, err
+			}
 			go func() {
-				_, err := function(query, current, nil, slice)
+				if functionOptions.Context.Err() != nil {
+					return
+				}
+				_, err := function(query, current, functionOptions, slice)
 				if err != nil {
 					if query.options.errors != nil {
 						query.options.errors(err)
@@ -1425,52 +2343,50 @@ func FunExpr(query *Query, current Map, expr *sqlparser.FuncExpr) (any, error) {
 	case "spinasync":
 		{
 			if isimmediate {
-				return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("%s is an immediate function and it cannot be spinned asynchronously", name))
+				return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("%s is an immediate function and it cannot be spinned asynchronously", name))
 			}
 			slice, e := FuncArgReader(query, current, expr.Exprs)
 			if e != nil {
 				return nil, e
 			}
+			if err := CheckGoroutines(query); err != nil {
+				return nil, err
+			}
 			query.wg.Add(1)
 			go func() {
-				_, err := function(query, current, nil, slice)
+				defer query.wg.Done()
+				if functionOptions.Context.Err() != nil {
+					return
+				}
+				_, err := function(query, current, functionOptions, slice)
 				if err != nil {
 					if query.options.errors != nil {
 						query.options.errors(err)
 					}
 				}
-				query.wg.Done()
 			}()
 			return Ommit(true), nil
 		}
 	case "once":
 		{
 			name := fmt.Sprintf("%s.%s", strings.ToLower(expr.Qualifier.String()), expr.Name.Lowered())
-			rs, ok := query.singletonExecutions[name]
-			if !ok {
-				slice, e := FuncArgReader(query, current, expr.Exprs)
+			return query.singletonExecutions.Do(name, func() (any, error) {
+				slice, e := FunctionArgReader(query, current, expr.Exprs, islazy)
 				if e != nil {
 					return nil, e
 				}
-				rs, err := function(query, current, nil, slice)
-				if err != nil {
-					return nil, err
-				}
-				query.singletonExecutions[name] = rs
-				return rs, nil
-			}
-			return rs, nil
+				return function(query, current, functionOptions, slice)
+			})
 		}
 	case "global":
 		{
 			name := fmt.Sprintf("%s.%s", strings.ToLower(expr.Qualifier.String()), expr.Name.Lowered())
-			rs, ok := query.singletonExecutions[name]
-			if !ok {
+			return query.singletonExecutions.Do(name, func() (any, error) {
 				exprs := make(sqlparser.Exprs, 0)
 				for _, expr := range expr.Exprs {
 					aliasedExpr, ok := expr.(*sqlparser.AliasedExpr)
 					if !ok {
-						return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to build global `FUNCTION`. expected aliased expression but found %T", expr))
+						return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build global `FUNCTION`. expected aliased expression but found %T", expr))
 					}
 					exprs = append(exprs, aliasedExpr.Expr)
 				}
@@ -1478,64 +2394,54 @@ func FunExpr(query *Query, current Map, expr *sqlparser.FuncExpr) (any, error) {
 				if err != nil {
 					return nil, err
 				}
-				rs, err := function(query, current, nil, slice)
-				if err != nil {
-					return nil, err
-				}
-				query.singletonExecutions[name] = rs
-				return rs, nil
-			}
-			return rs, nil
+				return function(query, current, functionOptions, slice)
+			})
 		}
 	case "scoped":
 		{
-			slice, e := FuncArgReader(query, current, expr.Exprs)
+			slice, e := FunctionArgReader(query, current, expr.Exprs, islazy)
 			if e != nil {
 				return nil, e
 			}
-			return function(query, current, nil, slice)
+			return function(query, current, functionOptions, slice)
 		}
 	default:
 		{
-			slice, e := FuncArgReader(query, current, expr.Exprs)
+			slice, e := FunctionArgReader(query, current, expr.Exprs, islazy)
 			if e != nil {
 				return nil, e
 			}
-			return function(query, current, nil, slice)
+			return function(query, current, functionOptions, slice)
 		}
 	}
 }
 func AggrFunExpr(query *Query, current Map, expr sqlparser.AggrFunc) (any, error) {
 	name := strings.ToLower(expr.AggrName())
-	function, ok := functions[name]
+	function, ok := query.Registry().Function(name)
 	if !ok {
-		return nil, INVALID_FUNCTION.Extend(fmt.Sprintf("function %s cannot be found", expr.AggrName()))
+		return nil, INVALID_FUNCTION.Describe(fmt.Sprintf("function %s cannot be found", expr.AggrName()))
+	}
+	functionOptions := &FunctionOptions{
+		Context: query.Context(),
 	}
 	if len(query.groupDefinition) != 0 {
 		slice, err := AggrFuncArgReader(query, current, expr.GetArgs())
 		if err != nil {
 			return nil, err
 		}
-		result, err := function(query, current, nil, slice)
+		result, err := function(query, current, functionOptions, slice)
 		if err != nil {
 			return nil, err
 		}
 		return result, nil
 	}
-	rs, ok := query.singletonExecutions[name]
-	if !ok {
+	return query.singletonExecutions.Do(name, func() (any, error) {
 		slice, err := AggrFuncArgReader(query, map[string]any{"*": query.from}, expr.GetArgs())
 		if err != nil {
 			return nil, err
 		}
-		result, err := function(query, current, nil, slice)
-		if err != nil {
-			return nil, err
-		}
-		query.singletonExecutions[name] = result
-		return result, nil
-	}
-	return rs, nil
+		return function(query, current, functionOptions, slice)
+	})
 }
 
 func FuncArgReader(query *Query, current Map, selectExprs sqlparser.SelectExprs) ([]any, error) {
@@ -1543,7 +2449,7 @@ func FuncArgReader(query *Query, current Map, selectExprs sqlparser.SelectExprs)
 	for _, expr := range selectExprs {
 		aliasedExpr, ok := expr.(*sqlparser.AliasedExpr)
 		if !ok {
-			return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to build `FUNCTION ARGUMENT`. expected aliased expression but found %T", expr))
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to build `FUNCTION ARGUMENT`. expected aliased expression but found %T", expr))
 		}
 		exprs = append(exprs, aliasedExpr.Expr)
 	}
@@ -1578,7 +2484,7 @@ func AggrFuncArgReader(query *Query, current Map, exprs sqlparser.Exprs) ([]any,
 					data = slice
 				}
 			}
-			rs, err := ExecReader(data, string(columnName))
+			rs, err := query.Registry().ExecReader(data, string(columnName))
 			if err != nil {
 				return nil, err
 			}
@@ -1598,11 +2504,11 @@ func ExecWhere(query *Query, current Map) (bool, error) {
 	if query.whereDefinition != nil {
 		rs, err := Expr(query, current, query.whereDefinition.Expr, nil)
 		if err != nil {
-			return false, err
+			return false, Annotate(err, "WHERE", query.whereDefinition.Expr)
 		}
-		result, ok := rs.(bool)
-		if !ok {
-			return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `WHERE` expression. expected a boolean but found %T", result))
+		result, err := IsMatch(query, current, rs, "WHERE")
+		if err != nil {
+			return false, Annotate(err, "WHERE", query.whereDefinition.Expr)
 		}
 		return result, nil
 	}
@@ -1613,44 +2519,39 @@ func ExecGroupBy(query *Query, current []any) ([]any, error) {
 	if len(query.groupDefinition) == 0 {
 		return current, nil
 	}
-	grouped := make(map[*map[string]any][]any)
+	// Groups are kept in the order their first row appears in
+	groups := make(map[string]int)
+	keys := make([]Map, 0)
+	rows := make([][]any, 0)
 	for _, item := range current {
-		innerMap := make(map[string]any)
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
+		values := make(Map)
 		for key := range query.groupDefinition {
-			rs, err := ExecReader(item, key)
+			rs, err := GroupValue(query, item, key)
 			if err != nil {
-				return nil, err
+				return nil, Annotate(err, "GROUP BY", query.groupExpressions[key])
 			}
-			innerMap[key] = rs
+			values[key] = rs
 		}
-		var ref *map[string]any
-		for group := range grouped {
-			isMatch := true
-			for key, value := range innerMap {
-				if (*group)[key] != value {
-					isMatch = false
-					break
-				}
-			}
-			if isMatch {
-				ref = group
-				break
-			}
-		}
-		if ref != nil {
-			grouped[ref] = append(grouped[ref], item)
-			continue
+		hash := StructuralKey(values)
+		index, ok := groups[hash]
+		if !ok {
+			index = len(keys)
+			groups[hash] = index
+			keys = append(keys, values)
+			rows = append(rows, make([]any, 0))
 		}
-		grouped[&innerMap] = make([]any, 0)
-		grouped[&innerMap] = append(grouped[&innerMap], item)
+		rows[index] = append(rows[index], item)
 	}
 	slice := make([]any, 0)
-	for key, item := range grouped {
+	for index, values := range keys {
 		current := make(Map)
-		for innerKey, innerValue := range *key {
-			current[innerKey] = innerValue
+		for key, value := range values {
+			current[key] = value
 		}
-		current["*"] = item
+		current["*"] = rows[index]
 		rs, err := ExecHaving(query, current)
 		if err != nil {
 			return nil, err
@@ -1658,20 +2559,37 @@ func ExecGroupBy(query *Query, current []any) ([]any, error) {
 		if rs {
 			slice = append(slice, current)
 		}
-
 	}
 	return slice, nil
 }
 
+// GroupValue reads the value of a GROUP BY key from a row, evaluating the
+// expression the key stands for if there is one
+func GroupValue(query *Query, item any, key string) (any, error) {
+	expr, ok := query.groupExpressions[key]
+	if !ok {
+		return query.Registry().ExecReader(item, key)
+	}
+	row, ok := item.(Map)
+	if !ok {
+		return nil, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `GROUP BY` clause. cannot evaluate expressions against %T", item))
+	}
+	rs, err := Expr(query, row, expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ValueOf(query, row, rs)
+}
+
 func ExecHaving(query *Query, current Map) (bool, error) {
 	if query.havingDefinition != nil {
 		rs, err := Expr(query, current, query.havingDefinition.Expr, nil)
 		if err != nil {
-			return false, err
+			return false, Annotate(err, "HAVING", query.havingDefinition.Expr)
 		}
-		result, ok := rs.(bool)
-		if !ok {
-			return false, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `HAVING` expression. expected a boolean but found %T", result))
+		result, err := IsMatch(query, current, rs, "HAVING")
+		if err != nil {
+			return false, Annotate(err, "HAVING", query.havingDefinition.Expr)
 		}
 		return result, nil
 	}
@@ -1701,13 +2619,16 @@ func ExecSelect(query *Query, current []any) ([]any, error) {
 		copy = append(copy, rs)
 		return copy, nil
 	}
-	for _, current := range current {
+	for index, current := range current {
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
 		switch current := current.(type) {
 		case []any:
 			{
 				rs, err := ExecSelect(query, current)
 				if err != nil {
-					return nil, err
+					return nil, AnnotateRow(err, index)
 				}
 				copy = append(copy, rs)
 			}
@@ -1715,13 +2636,13 @@ func ExecSelect(query *Query, current []any) ([]any, error) {
 			{
 				rs, err := SelectExpr(query, current, &query.selectDefinition)
 				if err != nil {
-					return nil, err
+					return nil, AnnotateRow(err, index)
 				}
 				copy = append(copy, rs)
 			}
 		default:
 			{
-				return nil, INVALID_TYPE.Extend(fmt.Sprintf("failed to build `SELECT` statement. cannot select from %T", current))
+				return nil, INVALID_TYPE.Describe(fmt.Sprintf("failed to build `SELECT` statement. cannot select from %T", current))
 			}
 		}
 	}
@@ -1750,24 +2671,95 @@ func ExecDistinct(query *Query, current []any) ([]any, error) {
 }
 
 func ExecOrderBy(query *Query, current []any) ([]any, error) {
-	if query.orderByDefinition == nil {
+	return ExecOrderByFrom(query, current, nil)
+}
+
+// ExecOrderByFrom sorts the selected rows. Keys that are not selected are
+// evaluated against the rows they were selected from, which are given in
+// the same order as the selected rows when available.
+func ExecOrderByFrom(query *Query, current []any, source []any) ([]any, error) {
+	if len(query.orderByDefinition) == 0 {
 		return current, nil
 	}
-	err := Sort(current, query.orderByDefinition)
+	if len(source) != len(current) {
+		source = nil
+	}
+	values := make([][]any, len(current))
+	for index, item := range current {
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
+		var from any
+		if source != nil {
+			from = source[index]
+		}
+		rs, err := OrderValues(query, item, from)
+		if err != nil {
+			return nil, AnnotateRow(Annotate(err, "ORDER BY", nil), index)
+		}
+		values[index] = rs
+	}
+	err := SortValues(current, values, query.orderByDefinition)
 	if err != nil {
-		return nil, err
+		return nil, Annotate(err, "ORDER BY", nil)
 	}
 	return current, nil
 }
 
+// OrderValues reads the ORDER BY keys of a selected row. A key that is not
+// selected is evaluated against the row it was selected from.
+func OrderValues(query *Query, item any, source any) ([]any, error) {
+	values := make([]any, len(query.orderByDefinition))
+	for index, orderBy := range query.orderByDefinition {
+		if row, ok := item.(Map); ok {
+			if value, ok := row[orderBy.Key]; ok {
+				values[index] = value
+				continue
+			}
+		}
+		var expr sqlparser.Expr
+		if index < len(query.orderByExpressions) {
+			expr = query.orderByExpressions[index]
+		}
+		if row, ok := source.(Map); ok && expr != nil {
+			rs, err := Expr(query, row, expr, nil)
+			if err != nil {
+				return nil, Annotate(err, "ORDER BY", expr)
+			}
+			value, err := ValueOf(query, row, rs)
+			if err != nil {
+				return nil, Annotate(err, "ORDER BY", expr)
+			}
+			values[index] = value
+			continue
+		}
+		if _, ok := expr.(*sqlparser.ColName); expr != nil && !ok {
+			continue
+		}
+		value, err := query.Registry().ExecReader(item, orderBy.Key)
+		if err != nil {
+			return nil, err
+		}
+		values[index] = value
+	}
+	return values, nil
+}
+
 func (query *Query) exec() (result any, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = r.(error)
 		}
 	}()
+	if query.dmlDefinition != nil {
+		return ExecDml(query)
+	}
 	if query.dual {
-		rs, err := ExecSelect(query, query.from)
+		rs, err := ExecWindows(query, query.from)
+		if err != nil {
+			return nil, err
+		}
+		rs, err = ExecSelect(query, rs)
 		if err != nil {
 			return nil, err
 		}
@@ -1777,7 +2769,10 @@ func (query *Query) exec() (result any, err error) {
 		return rs[0], nil
 	}
 	slice := make([]any, 0)
-	for _, current := range query.from {
+	for index, current := range query.from {
+		if err := query.contextErr(); err != nil {
+			return nil, err
+		}
 		switch current := current.(type) {
 		case []any:
 			{
@@ -1785,7 +2780,7 @@ func (query *Query) exec() (result any, err error) {
 				copy.from = current
 				rs, err := copy.exec()
 				if err != nil {
-					return nil, err
+					return nil, AnnotateRow(err, index)
 				}
 				slice = append(slice, rs)
 			}
@@ -1793,7 +2788,7 @@ func (query *Query) exec() (result any, err error) {
 			{
 				isMatch, err := ExecWhere(query, current)
 				if err != nil {
-					return nil, err
+					return nil, AnnotateRow(err, index)
 				}
 				if !isMatch {
 					continue
@@ -1811,15 +2806,23 @@ func (query *Query) exec() (result any, err error) {
 	if query.offsetDefinition != -1 {
 		offset = query.offsetDefinition
 	}
+	rs, err = ExecWindows(query, rs)
+	if err != nil {
+		return nil, err
+	}
+	source := rs
 	rs, err = ExecSelect(query, rs)
 	if err != nil {
 		return nil, err
 	}
+	if query.distinct || IsSelectAllAggregate(query) {
+		source = nil
+	}
 	rs, err = ExecDistinct(query, rs)
 	if err != nil {
 		return nil, err
 	}
-	rs, err = ExecOrderBy(query, rs)
+	rs, err = ExecOrderByFrom(query, rs, source)
 	if err != nil {
 		return nil, err
 	}
@@ -1847,7 +2850,10 @@ func (query *Query) execAndPostProcess() (result any, err error) {
 	if err != nil {
 		return nil, err
 	}
-	query.wg.Wait()
+	err = query.wait()
+	if err != nil {
+		return nil, err
+	}
 	for _, postProcessor := range query.postProcessors {
 		err := postProcessor()
 		if err != nil {
@@ -1857,69 +2863,120 @@ func (query *Query) execAndPostProcess() (result any, err error) {
 	return rs, nil
 }
 
-func (query *Query) Exec() (result []any, err error) {
-	rs, err := query.execAndPostProcess()
+// wait blocks until every asynchronous function started by the query has
+// returned or the context of the query is done
+func (query *Query) wait() error {
+	done := make(chan struct{})
+	go func() {
+		query.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		{
+			return nil
+		}
+	case <-query.Context().Done():
+		{
+			return query.contextErr()
+		}
+	}
+}
+
+// Exec executes the query. Params are bound to `?` and `$n` placeholders by
+// position and to `:name` placeholders when passed through Named.
+func (query *Query) Exec(params ...any) (result []any, err error) {
+	return query.ExecContext(context.Background(), params...)
+}
+
+func (query *Query) ExecContext(ctx context.Context, params ...any) (result []any, err error) {
+	run, cancel := query.start(ctx, params)
+	defer cancel()
+	defer func() {
+		err = run.timeoutErr(err)
+	}()
+	if run.explain {
+		return []any{run.Explain().Map()}, nil
+	}
+	err = ExecFrom(run)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := run.execAndPostProcess()
 	if err != nil {
 		return nil, err
 	}
-	if slice, ok := rs.([]any); ok {
-		return slice, nil
+	slice, ok := rs.([]any)
+	if !ok {
+		slice = []any{rs}
+	}
+	err = CheckResultRows(run, len(slice))
+	if err != nil {
+		return nil, err
 	}
-	return []any{rs}, nil
+	return slice, nil
 }
 
 func (query *Query) IsDual() bool {
 	return query.dual
 }
 
+// Context returns the context the query is being executed with. Custom
+// functions also receive it through FunctionOptions.
+func (query *Query) Context() context.Context {
+	if query.ctx == nil {
+		return context.Background()
+	}
+	return query.ctx
+}
+
+// RegexComparison matches a value against a LIKE pattern, ignoring case
 func RegexComparison(left any, pattern string) (bool, error) {
-	regExpr := strings.ReplaceAll(strings.ToLower(pattern), "_", ".")
-	regExpr = strings.ReplaceAll(regExpr, "%", ".*")
-	regExpr = "^" + regExpr + "$"
-	return regexp.Match(regExpr, []byte(strings.ToLower(fmt.Sprintf("%v", left))))
+	return regexp.MatchString("(?i)"+LikeRegexp(pattern, "\\"), fmt.Sprintf("%v", left))
 }
 
 func RegisterFunction(name string, function Function) {
-	if functions == nil {
-		functions = make(map[string]Function)
-		immediateFunctions = make([]string, 0)
-	}
-	functions[strings.ToLower(name)] = function
+	defaultRegistry.RegisterFunction(name, function)
 }
 
 func RegisterImmediateFunction(name string, function Function) {
-	RegisterFunction(name, function)
-	immediateFunctions = append(immediateFunctions, strings.ToLower(name))
+	defaultRegistry.RegisterImmediateFunction(name, function)
+}
+
+func RegisterLazyFunction(name string, function Function) {
+	defaultRegistry.RegisterLazyFunction(name, function)
 }
 
 func RegisterExternalFunction(name string, function func([]any) (any, error)) {
-	if functions == nil {
-		functions = make(map[string]Function)
-		immediateFunctions = make([]string, 0)
-	}
-	functions[strings.ToLower(name)] = func(_ *Query, _ Map, _ *FunctionOptions, args []any) (any, error) {
-		return function(args)
-	}
+	defaultRegistry.RegisterExternalFunction(name, function)
 }
 
 func Import(functions map[string]func([]any) (any, error)) {
-	for name, function := range functions {
-		RegisterExternalFunction(name, function)
-	}
+	defaultRegistry.Import(functions)
 }
 
 func CopyQuery(query *Query) *Query {
 	return &Query{
-		data:              query.data,
-		from:              query.from,
-		groupDefinition:   query.groupDefinition,
-		havingDefinition:  query.havingDefinition,
-		whereDefinition:   query.havingDefinition,
-		selectDefinition:  query.selectDefinition,
-		limitDefinition:   query.limitDefinition,
-		offsetDefinition:  query.offsetDefinition,
-		orderByDefinition: query.orderByDefinition,
-		options:           query.options,
-		postProcessors:    query.postProcessors,
+		data:                query.data,
+		from:                query.from,
+		groupDefinition:     query.groupDefinition,
+		groupExpressions:    query.groupExpressions,
+		groupLookup:         query.groupLookup,
+		havingDefinition:    query.havingDefinition,
+		whereDefinition:     query.havingDefinition,
+		selectDefinition:    query.selectDefinition,
+		limitDefinition:     query.limitDefinition,
+		offsetDefinition:    query.offsetDefinition,
+		orderByDefinition:   query.orderByDefinition,
+		orderByExpressions:  query.orderByExpressions,
+		windowDefinitions:   query.windowDefinitions,
+		windowLookup:        query.windowLookup,
+		options:             query.options,
+		postProcessors:      query.postProcessors,
+		singletonExecutions: newSingletons(),
+		ctx:                 query.ctx,
+		params:              query.params,
+		execution:           query.execution,
+		depth:               query.depth,
 	}
 }
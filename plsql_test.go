@@ -25,9 +25,12 @@
 package genql
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
 )
@@ -444,6 +447,74 @@ func TestExecGroupBy(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Group By First Appearance",
+			query: &Query{
+				groupDefinition: GroupDefinition{
+					"category": true,
+				},
+			},
+			input: []any{
+				Map{"category": "C", "value": 1},
+				Map{"category": "A", "value": 2},
+				Map{"category": "C", "value": 3},
+				Map{"category": "B", "value": 4},
+			},
+			want: []any{
+				Map{
+					"category": "C",
+					"*": []any{
+						Map{"category": "C", "value": 1},
+						Map{"category": "C", "value": 3},
+					},
+				},
+				Map{
+					"category": "A",
+					"*": []any{
+						Map{"category": "A", "value": 2},
+					},
+				},
+				Map{
+					"category": "B",
+					"*": []any{
+						Map{"category": "B", "value": 4},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Group By Non Comparable Values",
+			query: &Query{
+				groupDefinition: GroupDefinition{
+					"tags": true,
+					"id":   true,
+				},
+			},
+			input: []any{
+				Map{"tags": []any{"x", Map{"y": 1}}, "id": 1},
+				Map{"tags": []any{"x", Map{"y": 1.0}}, "id": 1.0},
+				Map{"tags": []any{"x"}, "id": 1},
+			},
+			want: []any{
+				Map{
+					"tags": []any{"x", Map{"y": 1}},
+					"id":   1,
+					"*": []any{
+						Map{"tags": []any{"x", Map{"y": 1}}, "id": 1},
+						Map{"tags": []any{"x", Map{"y": 1.0}}, "id": 1.0},
+					},
+				},
+				Map{
+					"tags": []any{"x"},
+					"id":   1,
+					"*": []any{
+						Map{"tags": []any{"x"}, "id": 1},
+					},
+				},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -460,6 +531,488 @@ func TestExecGroupBy(t *testing.T) {
 	}
 }
 
+func TestGroupAndOrderByExpressions(t *testing.T) {
+	data := Map{
+		"items": []any{
+			Map{"name": "Bob", "category": "A", "price": 3.0, "qty": 2.0},
+			Map{"name": "bob", "category": "B", "price": 1.0, "qty": 5.0},
+			Map{"name": "alice", "category": "A", "price": 10.0, "qty": 1.0},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Group By Function",
+			query: "SELECT to_lower(name) AS name, count(*) AS c FROM `root.items` GROUP BY to_lower(name)",
+			want:  "[map[c:2 name:bob] map[c:1 name:alice]]",
+		},
+		{
+			name:  "Group By Arithmetic",
+			query: "SELECT price * qty AS total FROM `root.items` GROUP BY price * qty ORDER BY total",
+			want:  "[map[total:5] map[total:6] map[total:10]]",
+		},
+		{
+			name:  "Group By Case",
+			query: "SELECT CASE WHEN price > 2 THEN 'high' ELSE 'low' END AS band, count(*) AS c FROM `root.items` GROUP BY CASE WHEN price > 2 THEN 'high' ELSE 'low' END",
+			want:  "[map[band:high c:2] map[band:low c:1]]",
+		},
+		{
+			name:  "Group By Alias",
+			query: "SELECT to_lower(name) AS n, sum(qty) AS q FROM `root.items` GROUP BY n",
+			want:  "[map[n:bob q:7] map[n:alice q:1]]",
+		},
+		{
+			name:  "Group By Ordinal",
+			query: "SELECT to_lower(name) AS n, sum(qty) AS q FROM `root.items` GROUP BY 1",
+			want:  "[map[n:bob q:7] map[n:alice q:1]]",
+		},
+		{
+			name:  "Group By Column Ordinal",
+			query: "SELECT category, count(*) AS c FROM `root.items` GROUP BY 1",
+			want:  "[map[c:2 category:A] map[c:1 category:B]]",
+		},
+		{
+			name:  "Order By Expression",
+			query: "SELECT name FROM `root.items` ORDER BY price * qty DESC",
+			want:  "[map[name:alice] map[name:Bob] map[name:bob]]",
+		},
+		{
+			name:  "Order By Selected Expression",
+			query: "SELECT name, price * qty FROM `root.items` ORDER BY price * qty",
+			want:  "[map[name:bob price * qty:5] map[name:Bob price * qty:6] map[name:alice price * qty:10]]",
+		},
+		{
+			name:  "Order By Alias",
+			query: "SELECT name, price * qty AS total FROM `root.items` ORDER BY total DESC",
+			want:  "[map[name:alice total:10] map[name:Bob total:6] map[name:bob total:5]]",
+		},
+		{
+			name:  "Order By Ordinal",
+			query: "SELECT name, price FROM `root.items` ORDER BY 2",
+			want:  "[map[name:bob price:1] map[name:Bob price:3] map[name:alice price:10]]",
+		},
+		{
+			name:  "Order By Column Not Selected",
+			query: "SELECT name FROM `root.items` ORDER BY qty",
+			want:  "[map[name:alice] map[name:Bob] map[name:bob]]",
+		},
+		{
+			name:  "Order By Aggregate",
+			query: "SELECT category FROM `root.items` GROUP BY category ORDER BY sum(price) ASC",
+			want:  "[map[category:B] map[category:A]]",
+		},
+		{
+			name:  "Order By Is Stable",
+			query: "SELECT name FROM `root.items` ORDER BY category",
+			want:  "[map[name:Bob] map[name:alice] map[name:bob]]",
+		},
+		{
+			name:    "Ordinal Out Of Range",
+			query:   "SELECT name FROM `root.items` ORDER BY 2",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Ordinal Of Star",
+			query:   "SELECT * FROM `root.items` GROUP BY 1",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Group By Aggregate",
+			query:   "SELECT category FROM `root.items` GROUP BY count(*)",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Group By Constant",
+			query:   "SELECT category FROM `root.items` GROUP BY 'A'",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Distinct Order By Column Not Selected",
+			query:   "SELECT DISTINCT name FROM `root.items` ORDER BY qty",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:  "Distinct Order By Selected Column",
+			query: "SELECT DISTINCT category, name FROM `root.items` ORDER BY category DESC, name",
+			want:  "[map[category:B name:bob] map[category:A name:Bob] map[category:A name:alice]]",
+		},
+		{
+			name:    "Distinct Order By Expression",
+			query:   "SELECT DISTINCT name FROM `root.items` ORDER BY price * 2",
+			wantErr: EXPECTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, Wrapped())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderByComparesLikeWhere(t *testing.T) {
+	data := Map{
+		"rows": []any{
+			Map{"code": "10"},
+			Map{"code": 9},
+			Map{"code": " 8.5"},
+		},
+	}
+	for query, want := range map[string]string{
+		"SELECT code FROM `root.rows` ORDER BY code":                     "[map[code: 8.5] map[code:9] map[code:10]]",
+		"SELECT code FROM `root.rows` WHERE code > 9":                    "[map[code:10]]",
+		"SELECT code FROM `root.rows` WHERE code > 9 ORDER BY code DESC": "[map[code:10]]",
+	} {
+		q, err := New(data, query, Wrapped())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		result, err := q.Exec()
+		if err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+		if fmt.Sprintf("%v", result) != want {
+			t.Errorf("%s = %v, want %v", query, result, want)
+		}
+	}
+}
+
+func TestThreeValuedLogic(t *testing.T) {
+	data := Map{
+		"rows": []any{
+			Map{"id": 1.0, "a": 1.0, "b": 3.0},
+			Map{"id": 2.0, "a": 1.0},
+			Map{"id": 3.0, "b": 1.0},
+		},
+		"tags": []any{
+			Map{"id": 1.0, "tag": "x"},
+			Map{"id": 2.0},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		options []QueryOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "And",
+			query: "SELECT id, a = 1 AND b > 2 AS r FROM `root.rows`",
+			want:  "[map[id:1 r:true] map[id:2 r:<nil>] map[id:3 r:false]]",
+		},
+		{
+			name:  "Or",
+			query: "SELECT id, a = 1 OR b > 2 AS r FROM `root.rows`",
+			want:  "[map[id:1 r:true] map[id:2 r:true] map[id:3 r:<nil>]]",
+		},
+		{
+			name:  "Not",
+			query: "SELECT id, NOT b > 2 AS r FROM `root.rows`",
+			want:  "[map[id:1 r:false] map[id:2 r:<nil>] map[id:3 r:true]]",
+		},
+		{
+			name:  "Where",
+			query: "SELECT id FROM `root.rows` WHERE a = 1 AND b > 2",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Where Not",
+			query: "SELECT id FROM `root.rows` WHERE NOT (b > 2)",
+			want:  "[map[id:3]]",
+		},
+		{
+			name:  "Having",
+			query: "SELECT a, count(*) AS c FROM `root.rows` GROUP BY a HAVING a > 0",
+			want:  "[map[a:1 c:2]]",
+		},
+		{
+			name:  "Join",
+			query: "SELECT r.id, t.tag FROM `root.rows` r JOIN `root.tags` t ON r.id = t.id AND t.tag = 'x'",
+			want:  "[map[id:1 tag:x]]",
+		},
+		{
+			name:  "Case",
+			query: "SELECT id, CASE WHEN b > 2 THEN 'high' ELSE 'other' END AS r FROM `root.rows`",
+			want:  "[map[id:1 r:high] map[id:2 r:other] map[id:3 r:other]]",
+		},
+		{
+			name:    "Strict",
+			query:   "SELECT id FROM `root.rows` WHERE a = 1 AND NOT c",
+			options: []QueryOption{StrictNulls()},
+			wantErr: EXPECTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, append([]QueryOption{Wrapped()}, tt.options...)...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	data := Map{
+		"rows": []any{
+			Map{"id": 1.0, "n": 10.0, "name": "bob", "at": "2024-03-01T10:00:00Z", "low": 1.0, "high": 10.0, "age": 17},
+			Map{"id": 2.0, "n": 100.0, "name": "alice", "at": "2024-03-01T12:30:00+02:00", "low": 200.0, "high": 50.0, "code": "10"},
+			Map{"id": 3.0, "name": "zed", "at": "2024-02-29"},
+		},
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Numbers",
+			query: "SELECT id FROM `root.rows` WHERE n BETWEEN 9 AND 100",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Inclusive",
+			query: "SELECT id, n BETWEEN 10 AND 10 AS r FROM `root.rows` WHERE id = 1",
+			want:  "[map[id:1 r:true]]",
+		},
+		{
+			name:  "Column Bounds",
+			query: "SELECT id, n BETWEEN low AND high AS r FROM `root.rows`",
+			want:  "[map[id:1 r:true] map[id:2 r:false] map[id:3 r:<nil>]]",
+		},
+		{
+			name:  "Strings",
+			query: "SELECT id FROM `root.rows` WHERE name BETWEEN 'alice' AND 'bob'",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Timestamps",
+			query: "SELECT id FROM `root.rows` WHERE at BETWEEN '2024-03-01T10:00:00Z' AND '2024-03-01 10:30:00'",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Dates",
+			query: "SELECT id FROM `root.rows` WHERE at BETWEEN '2024-02-01' AND '2024-02-29'",
+			want:  "[map[id:3]]",
+		},
+		{
+			name:  "Not Between",
+			query: "SELECT id FROM `root.rows` WHERE n NOT BETWEEN 9 AND 99",
+			want:  "[map[id:2]]",
+		},
+		{
+			name:  "Reversed Bounds",
+			query: "SELECT id FROM `root.rows` WHERE n BETWEEN 100 AND 9",
+			want:  "[]",
+		},
+		{
+			name:  "Symmetric",
+			query: "SELECT id, n BETWEEN SYMMETRIC high AND low AS r FROM `root.rows`",
+			want:  "[map[id:1 r:true] map[id:2 r:true] map[id:3 r:<nil>]]",
+		},
+		{
+			name:  "Int Against Fractional Bounds",
+			query: "SELECT id, age BETWEEN 17.5 AND 40 AS above, age BETWEEN 16.5 AND 17 AS within FROM `root.rows` WHERE id = 1",
+			want:  "[map[above:false id:1 within:true]]",
+		},
+		{
+			name:  "Numeric String",
+			query: "SELECT id, '10' BETWEEN 9 AND 100 AS literal, code BETWEEN 9 AND 100 AS r FROM `root.rows` WHERE id = 2",
+			want:  "[map[id:2 literal:true r:true]]",
+		},
+		{
+			name:  "Numeric String Bounds",
+			query: "SELECT id FROM `root.rows` WHERE n BETWEEN '9' AND '99'",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Not Between Symmetric",
+			query: "SELECT id FROM `root.rows` WHERE n NOT BETWEEN SYMMETRIC 100 AND 9",
+			want:  "[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, Wrapped())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuantifiedComparison(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "ann", "score": 90.0, "roles": []any{"admin", "dev"}, "age": 17},
+			Map{"id": 2.0, "name": "ben", "score": 40.0, "roles": []any{"dev"}},
+			Map{"id": 3.0, "name": "cid", "roles": []any{}},
+		},
+		"banned": []any{Map{"id": 2.0}, Map{"id": nil}},
+		"limits": []any{Map{"score": 30.0}, Map{"score": 60.0}},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		options []QueryOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "In",
+			query: "SELECT id FROM `root.users` WHERE id IN (1, '3')",
+			want:  "[map[id:1] map[id:3]]",
+		},
+		{
+			name:  "Not In",
+			query: "SELECT id FROM `root.users` WHERE id NOT IN (1, 3)",
+			want:  "[map[id:2]]",
+		},
+		{
+			name:  "Not In With Null",
+			query: "SELECT id, id NOT IN (2, NULL) AS r FROM `root.users`",
+			want:  "[map[id:1 r:<nil>] map[id:2 r:false] map[id:3 r:<nil>]]",
+		},
+		{
+			name:  "Not In Subquery With Null",
+			query: "SELECT id FROM `root.users` WHERE id NOT IN (SELECT id FROM `<-root.banned`)",
+			want:  "[]",
+		},
+		{
+			name:  "In Subquery",
+			query: "SELECT id FROM `root.users` WHERE id IN (SELECT id FROM `<-root.banned`)",
+			want:  "[map[id:2]]",
+		},
+		{
+			name:  "Null In Empty List",
+			query: "SELECT id, score IN (roles) AS r FROM `root.users` WHERE id = 3",
+			want:  "[map[id:3 r:false]]",
+		},
+		{
+			name:  "In Array Column",
+			query: "SELECT id FROM `root.users` WHERE 'admin' IN roles",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Not In Array Column",
+			query: "SELECT id FROM `root.users` WHERE 'admin' NOT IN `roles`",
+			want:  "[map[id:2] map[id:3]]",
+		},
+		{
+			name:  "In Missing Column",
+			query: "SELECT id, 'admin' IN missing AS r FROM `root.users` WHERE id = 1",
+			want:  "[map[id:1 r:<nil>]]",
+		},
+		{
+			name:  "Equal Any",
+			query: "SELECT id FROM `root.users` WHERE id = ANY (SELECT id FROM `<-root.banned`)",
+			want:  "[map[id:2]]",
+		},
+		{
+			name:  "Greater Than Some",
+			query: "SELECT id FROM `root.users` WHERE score > SOME (SELECT score FROM `<-root.limits`)",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Greater Than All",
+			query: "SELECT id FROM `root.users` WHERE score > ALL (SELECT score FROM `<-root.limits`)",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "All Of Empty Array",
+			query: "SELECT id, 'admin' <> ALL (roles) AS r FROM `root.users`",
+			want:  "[map[id:1 r:false] map[id:2 r:true] map[id:3 r:true]]",
+		},
+		{
+			name:  "Int Against Fraction",
+			query: "SELECT id, age IN (17.5) AS a, 17.5 IN (age) AS b, age = ANY (SELECT 17.5 AS v) AS c, 17 IN (age) AS d, '17' IN (age) AS e FROM `root.users` WHERE id = 1",
+			want:  "[map[a:false b:false c:false d:true e:true id:1]]",
+		},
+		{
+			name:  "Single Row Subquery",
+			query: "SELECT id FROM `root.users` WHERE name = ANY (SELECT 'ben' AS q)",
+			want:  "[map[id:2]]",
+		},
+		{
+			name:    "Single Row Subquery With Many Columns",
+			query:   "SELECT id FROM `root.users` WHERE name = ANY (SELECT 'axb' AS q, 'a%b' AS r)",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Subquery With Many Columns",
+			query:   "SELECT id FROM `root.users` WHERE id IN (SELECT id, score FROM `<-root.limits`)",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Strict Null Candidate",
+			query:   "SELECT id FROM `root.users` WHERE 'admin' IN missing",
+			options: []QueryOption{StrictNulls()},
+			want:    "[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, append([]QueryOption{Wrapped()}, tt.options...)...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
 func TestQuery_Exec(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -653,6 +1206,230 @@ func TestBuildJoin(t *testing.T) {
 	}
 }
 
+func TestBuildCrossJoin(t *testing.T) {
+	data := Map{
+		"orders": []any{
+			Map{"id": 1, "cid": 1, "total": 10},
+			Map{"id": 2, "cid": 2, "total": 20},
+			Map{"id": 3, "cid": 1, "total": 5},
+		},
+		"customers": []any{
+			Map{"id": 1, "name": "a"},
+			Map{"id": 2, "name": "b"},
+		},
+		"tags": []any{
+			Map{"tag": "x"},
+			Map{"tag": "y"},
+		},
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  string
+		join  string
+		where string
+	}{
+		{
+			name:  "Cartesian Product",
+			query: "SELECT c.name AS name, t.tag AS tag FROM `root.customers` c, `root.tags` t",
+			want:  "[map[name:a tag:x] map[name:a tag:y] map[name:b tag:x] map[name:b tag:y]]",
+			join:  "`root.customers` as c join `root.tags` as t",
+		},
+		{
+			name:  "Join Predicate",
+			query: "SELECT o.id AS id, c.name AS name FROM `root.orders` o, `root.customers` c WHERE o.cid = c.id",
+			want:  "[map[id:1 name:a] map[id:2 name:b] map[id:3 name:a]]",
+			join:  "`root.orders` as o join `root.customers` as c on o.cid = c.id",
+		},
+		{
+			name:  "Remaining Filter",
+			query: "SELECT o.id AS id, c.name AS name FROM `root.orders` o, `root.customers` c WHERE o.cid = c.id AND o.total > 6",
+			want:  "[map[id:1 name:a] map[id:2 name:b]]",
+			join:  "`root.orders` as o join `root.customers` as c on o.cid = c.id",
+			where: " where o.total > 6",
+		},
+		{
+			name:  "Three Tables",
+			query: "SELECT o.id AS id, t.tag AS tag FROM `root.orders` o, `root.customers` c, `root.tags` t WHERE o.cid = c.id AND c.name = 'b' AND t.tag = 'y'",
+			want:  "[map[id:2 tag:y]]",
+			join:  "`root.orders` as o join `root.customers` as c on o.cid = c.id and c.`name` = 'b' join `root.tags` as t on t.tag = 'y'",
+		},
+		{
+			name:  "Disjunction",
+			query: "SELECT o.id AS id, c.name AS name FROM `root.orders` o, `root.customers` c WHERE o.cid = c.id OR o.id = 3",
+			want:  "[map[id:1 name:a] map[id:2 name:b] map[id:3 name:a] map[id:3 name:b]]",
+			join:  "`root.orders` as o join `root.customers` as c on o.cid = c.id or o.id = 3",
+		},
+		{
+			name:  "Implicit Aliases",
+			query: "SELECT orders.id AS oid, customers.id AS cid FROM `root.orders`, `root.customers` WHERE orders.cid = customers.id AND orders.total > 6",
+			want:  "[map[cid:1 oid:1] map[cid:2 oid:2]]",
+			join:  "`root.orders` as orders join `root.customers` as customers on orders.cid = customers.id",
+			where: " where orders.total > 6",
+		},
+		{
+			name:  "Implicit Alias Next To Alias",
+			query: "SELECT o.id AS id, tags.tag AS tag FROM `root.orders` o, `root.tags` WHERE o.id = 1",
+			want:  "[map[id:1 tag:x] map[id:1 tag:y]]",
+			join:  "`root.orders` as o join `root.tags` as tags",
+			where: " where o.id = 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, Wrapped())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if join := sqlparser.String(q.fromDefinition); join != tt.join {
+				t.Errorf("BuildCrossJoin() join = %s, want %s", join, tt.join)
+			}
+			if where := sqlparser.String(q.whereDefinition); where != tt.where {
+				t.Errorf("BuildCrossJoin() where = %s, want %s", where, tt.where)
+			}
+			result, err := q.Exec()
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCrossJoin_DuplicateAlias(t *testing.T) {
+	_, err := New(Map{}, "SELECT * FROM `root.a.items`, `root.b.items`", Wrapped())
+	if !errors.Is(err, EXPECTATION_FAILED) {
+		t.Fatalf("New() error = %v, want %v", err, EXPECTATION_FAILED)
+	}
+}
+
+func TestOuterJoins(t *testing.T) {
+	data := Map{
+		"a": []any{
+			Map{"k": 1, "x": "a1"},
+			Map{"k": 2, "x": "a2"},
+		},
+		"b": []any{
+			Map{"k": 2, "y": "b2"},
+			Map{"k": 3, "y": "b3"},
+		},
+		"parents": []any{
+			Map{"id": 1, "items": []any{Map{"n": "i1"}, Map{"n": "i2"}}},
+			Map{"id": 2, "items": []any{}},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Full Outer Join",
+			query: "SELECT a.x AS x, b.y AS y FROM `root.a` a FULL OUTER JOIN `root.b` b ON a.k = b.k",
+			want:  "[map[x:a1 y:<nil>] map[x:a2 y:b2] map[x:<nil> y:b3]]",
+		},
+		{
+			name:  "Full Join Null Filling",
+			query: "SELECT * FROM `root.a` FULL JOIN `root.b` ON 1 = 0",
+			want:  "[map[a:map[k:1 x:a1] b:<nil>] map[a:map[k:2 x:a2] b:<nil>] map[a:<nil> b:map[k:2 y:b2]] map[a:<nil> b:map[k:3 y:b3]]]",
+		},
+		{
+			name:  "Full Join With Where",
+			query: "SELECT a.x AS x, b.y AS y FROM `root.a` a FULL JOIN `root.b` b ON a.k = b.k -- keeps both sides\nWHERE b.y = 'b3'",
+			want:  "[map[x:<nil> y:b3]]",
+		},
+		{
+			name:  "Straight Join",
+			query: "SELECT a.x AS x, b.y AS y FROM `root.a` a STRAIGHT_JOIN `root.b` b ON a.k = b.k",
+			want:  "[map[x:a2 y:b2]]",
+		},
+		{
+			name:  "Straight Join Next To Full Join",
+			query: "SELECT a.x AS x, b.y AS y, c.y AS z FROM `root.a` a STRAIGHT_JOIN `root.b` b ON a.k = b.k FULL JOIN `root.b` c ON b.k = c.k",
+			want:  "[map[x:a2 y:b2 z:b2] map[x:<nil> y:<nil> z:b3]]",
+		},
+		{
+			name:  "Left Join Null Filling",
+			query: "SELECT * FROM `root.a` a LEFT JOIN `root.b` b ON a.k = b.k",
+			want:  "[map[a:map[k:1 x:a1] b:<nil>] map[a:map[k:2 x:a2] b:map[k:2 y:b2]]]",
+		},
+		{
+			name:  "Cross Join",
+			query: "SELECT a.x AS x, b.y AS y FROM `root.a` a CROSS JOIN `root.b` b",
+			want:  "[map[x:a1 y:b2] map[x:a1 y:b3] map[x:a2 y:b2] map[x:a2 y:b3]]",
+		},
+		{
+			name:  "Cross Apply",
+			query: "SELECT p.id AS id, i.n AS n FROM `root.parents` p CROSS APPLY `p.items` i",
+			want:  "[map[id:1 n:i1] map[id:1 n:i2]]",
+		},
+		{
+			name:  "Lateral",
+			query: "SELECT p.id AS id, i.n AS n FROM `root.parents` p JOIN LATERAL (SELECT n FROM `p.items` WHERE n = 'i2') i",
+			want:  "[map[id:1 n:i2]]",
+		},
+		{
+			name:  "Left Join Lateral",
+			query: "SELECT p.id AS id, i.n AS n FROM `root.parents` p LEFT JOIN LATERAL (SELECT * FROM `p.items`) i ON TRUE",
+			want:  "[map[id:1 n:i1] map[id:1 n:i2] map[id:2 n:<nil>]]",
+		},
+		{
+			name:  "Comma Lateral",
+			query: "SELECT p.id AS id, i.n AS n FROM `root.parents` p, LATERAL (SELECT * FROM `p.items`) i",
+			want:  "[map[id:1 n:i1] map[id:1 n:i2]]",
+		},
+		{
+			name:    "Right Join Lateral",
+			query:   "SELECT p.id AS id FROM `root.parents` p RIGHT JOIN LATERAL (SELECT * FROM `p.items`) i ON TRUE",
+			wantErr: UNSUPPORTED_CASE,
+		},
+		{
+			name:  "Join With Implicit Aliases",
+			query: "SELECT a.x AS x, b.y AS y, a.k AS ak, b.k AS bk FROM `root.a` JOIN `root.b` ON a.k = b.k",
+			want:  "[map[ak:2 bk:2 x:a2 y:b2]]",
+		},
+		{
+			name:  "Join With Implicit And Explicit Aliases",
+			query: "SELECT a.x AS x, c.y AS y FROM `root.a` LEFT JOIN `root.b` c ON a.k = c.k",
+			want:  "[map[x:a1 y:<nil>] map[x:a2 y:b2]]",
+		},
+		{
+			name:    "Join With Duplicate Aliases",
+			query:   "SELECT * FROM `root.a` JOIN `root.a` ON TRUE",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Full Join Lateral",
+			query:   "SELECT p.id AS id FROM `root.parents` p FULL JOIN LATERAL (SELECT * FROM `p.items`) i ON TRUE",
+			wantErr: UNSUPPORTED_CASE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, Wrapped())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
 func TestAggregations(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1674,7 +2451,10 @@ func TestNotExpr(t *testing.T) {
 					{"value": nil},
 				},
 			},
-			wantErr: true,
+			want: []Map{
+				{"result": nil},
+			},
+			wantErr: false,
 		},
 		{
 			name: "NOT with non-boolean value",
@@ -1766,7 +2546,10 @@ func TestNotExpr(t *testing.T) {
 					{"value": true},
 				},
 			},
-			wantErr: true,
+			want: []Map{
+				{"result": nil},
+			},
+			wantErr: false,
 		},
 	}
 
@@ -2333,3 +3116,74 @@ func TestValueTupleExpr(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_ExecContext(t *testing.T) {
+	RegisterFunction("test_wait_for_context", func(query *Query, current Map, functionOptions *FunctionOptions, args []any) (any, error) {
+		select {
+		case <-functionOptions.Context.Done():
+			{
+				return nil, functionOptions.Context.Err()
+			}
+		case <-time.After(time.Second):
+			{
+				return true, nil
+			}
+		}
+	})
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		query   string
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{
+			name:  "Cancelled Before Execution",
+			query: "SELECT * FROM users",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return cancelled, func() {}
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name:  "Deadline Reaches Function",
+			query: "SELECT test_wait_for_context() AS waited FROM users",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:  "Deadline Stops Awaiting Async Functions",
+			query: "SELECT async.test_wait_for_context() AS waited FROM users",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:  "Completes Without Deadline",
+			query: "SELECT id FROM users",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			wantErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(Map{"users": []any{Map{"id": 1}, Map{"id": 2}}}, tt.query)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			_, err = q.ExecContext(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ExecContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package genql
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
 func DoubleQuotesToBackTick(str string) (string, error) {
@@ -179,3 +180,427 @@ func FixIdiomaticArray(input string) (string, error) {
 	}
 	return input, nil
 }
+
+// FullJoin is the function RewriteJoins wraps the condition of a full join
+// in (see JoinDefinition)
+const FullJoin = "genql_full_join"
+
+// RewriteJoins rewrites the joins the parser does not understand into forms
+// it does. FULL [OUTER] JOIN becomes a LEFT JOIN whose condition is wrapped
+// in a function marking it as a full join, and CROSS APPLY over a table
+// becomes a JOIN LATERAL over a derived table selecting from it:
+//
+//	a FULL JOIN b ON a.id = b.id  => a LEFT JOIN b ON genql_full_join(a.id = b.id)
+//	p CROSS APPLY `p.items` i     => p JOIN LATERAL (SELECT * FROM `p.items`) i
+func RewriteJoins(str string) (string, error) {
+	return RewriteQuery(str, JoinEdits)
+}
+
+// JoinEdits collects the edits of RewriteJoins
+func JoinEdits(str string, tokens Tokens, edits *Edits) error {
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case tokens.Is(i, "FULL"):
+			{
+				next := tokens.Next(i)
+				if tokens.Is(next, "OUTER") {
+					next = tokens.Next(next)
+				}
+				if !tokens.Is(next, "JOIN") {
+					break
+				}
+				on, end, err := JoinCondition(tokens, tokens.Next(next))
+				if err != nil {
+					return err
+				}
+				if on == -1 {
+					return fmt.Errorf("expected ON after FULL JOIN")
+				}
+				start := tokens.Next(on)
+				if start >= end {
+					return fmt.Errorf("expected a condition after ON")
+				}
+				edits.Replace(token.Start, tokens[next].End, "LEFT JOIN")
+				edits.Insert(tokens[start].Start, fmt.Sprintf("%s(", FullJoin))
+				edits.Insert(tokens[tokens.Previous(end)].End, ")")
+				i = next
+			}
+		case tokens.Is(i, "CROSS"):
+			{
+				next := tokens.Next(i)
+				if !tokens.Is(next, "APPLY") {
+					break
+				}
+				start := tokens.Next(next)
+				if tokens.IsSymbol(start, "(") {
+					edits.Replace(token.Start, tokens[start].Start, "JOIN LATERAL ")
+					i = next
+					break
+				}
+				end := tokens.NameEnd(start)
+				if end == start {
+					return fmt.Errorf("expected a table after CROSS APPLY")
+				}
+				table := str[tokens[start].Start:tokens[end-1].End]
+				edits.Replace(token.Start, tokens[end-1].End, fmt.Sprintf("JOIN LATERAL (SELECT * FROM %s)", table))
+				i = end - 1
+			}
+		}
+	}
+	return nil
+}
+
+// JoinCondition returns the index of the ON of the join whose table starts
+// at the token start, or -1 when it has none, and the index right after its
+// condition, which ends where the next join, the next clause of the query or
+// the enclosing parenthesis starts
+func JoinCondition(tokens Tokens, start int) (int, int, error) {
+	on := -1
+	for i := start; i < len(tokens); i++ {
+		switch {
+		case tokens.IsSymbol(i, "("):
+			{
+				close, err := tokens.Close(i)
+				if err != nil {
+					return 0, 0, err
+				}
+				i = close
+			}
+		case tokens.IsSymbol(i, "),;"):
+			{
+				return on, i, nil
+			}
+		case tokens.Is(i, "ON"):
+			{
+				if on == -1 {
+					on = i
+				}
+			}
+		case tokens.Is(i, "LEFT", "RIGHT") && tokens.IsSymbol(tokens.Next(i), "("):
+			{
+				// LEFT and RIGHT are also functions
+				continue
+			}
+		case tokens.Is(i, "JOIN", "INNER", "LEFT", "RIGHT", "CROSS", "STRAIGHT_JOIN", "NATURAL", "FULL", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "WINDOW", "UNION", "INTERSECT", "EXCEPT", "FOR", "LOCK", "INTO"):
+			{
+				return on, i, nil
+			}
+		}
+	}
+	return on, len(tokens), nil
+}
+
+// setOperation is a UNION, INTERSECT or EXCEPT keyword found by
+// SetOperationEdits, along with its ALL or DISTINCT modifier. start and end
+// are offsets in the query and next is the index of the token after it.
+type setOperation struct {
+	start    int
+	end      int
+	next     int
+	operator string
+	all      bool
+}
+
+// RewriteSetOperations rewrites INTERSECT and EXCEPT, which the parser does
+// not know, as UNION and tags the first SELECT of their right operand with
+// a comment naming the operation (see SetOperator):
+//
+//	SELECT a FROM x EXCEPT SELECT a FROM y
+//	SELECT a FROM x UNION SELECT /* genql:except */ a FROM y
+//
+// INTERSECT binds more tightly than UNION and EXCEPT, which are evaluated
+// from left to right, so a run of INTERSECT in the middle of a chain is put
+// in parentheses.
+func RewriteSetOperations(str string) (string, error) {
+	return RewriteQuery(str, SetOperationEdits)
+}
+
+// SetOperationEdits collects the edits of RewriteSetOperations
+func SetOperationEdits(str string, tokens Tokens, edits *Edits) error {
+	return ChainEdits(str, tokens, 0, len(tokens), edits)
+}
+
+// ChainEdits collects the edits made to the chain of set operations between
+// the tokens start and end. Parenthesised text holds chains of its own.
+func ChainEdits(str string, tokens Tokens, start int, end int, edits *Edits) error {
+	operations := make([]setOperation, 0)
+	rangeEnd := len(str)
+	if end < len(tokens) {
+		rangeEnd = tokens[end].Start
+	}
+	chainEnd := rangeEnd
+	for i := start; i < end; i++ {
+		token := tokens[i]
+		switch {
+		case tokens.IsSymbol(i, "("):
+			{
+				close, err := tokens.Close(i)
+				if err != nil {
+					return err
+				}
+				if err := ChainEdits(str, tokens, i+1, close, edits); err != nil {
+					return err
+				}
+				i = close
+			}
+		case tokens.Is(i, "UNION", "INTERSECT", "EXCEPT"):
+			{
+				operation := setOperation{start: token.Start, end: token.End, next: i + 1, operator: strings.ToUpper(token.Text)}
+				if next := tokens.Next(i); next < end && tokens.Is(next, "ALL", "DISTINCT") {
+					operation.all = tokens.Is(next, "ALL")
+					operation.end = tokens[next].End
+					operation.next = next + 1
+					i = next
+				}
+				operations = append(operations, operation)
+				chainEnd = rangeEnd
+			}
+		case tokens.Is(i, "ORDER", "LIMIT"):
+			{
+				if len(operations) != 0 && chainEnd == rangeEnd {
+					chainEnd = token.Start
+				}
+			}
+		}
+	}
+	for _, operation := range operations {
+		if operation.operator == "UNION" {
+			continue
+		}
+		selectEnd := SelectEnd(tokens, operation.next, end)
+		if selectEnd == -1 {
+			return fmt.Errorf("expected SELECT after %s", operation.operator)
+		}
+		replacement := "UNION"
+		if operation.all {
+			replacement = "UNION ALL"
+		}
+		edits.Replace(operation.start, operation.end, replacement)
+		edits.Insert(selectEnd, fmt.Sprintf(" /* genql:%s */", strings.ToLower(operation.operator)))
+	}
+	for i := 0; i < len(operations); i++ {
+		if operations[i].operator != "INTERSECT" {
+			continue
+		}
+		last := i
+		for last+1 < len(operations) && operations[last+1].operator == "INTERSECT" {
+			last++
+		}
+		if i != 0 {
+			edits.Insert(operations[i-1].end, " (")
+			if last+1 < len(operations) {
+				edits.Insert(operations[last+1].start, ") ")
+			} else {
+				edits.Insert(chainEnd, ") ")
+			}
+		}
+		i = last
+	}
+	return nil
+}
+
+// SelectEnd returns the offset right after the first SELECT keyword between
+// the tokens start and end, or -1 when there is none
+func SelectEnd(tokens Tokens, start int, end int) int {
+	for i := start; i < end; i++ {
+		if tokens.Is(i, "SELECT") {
+			return tokens[i].End
+		}
+	}
+	return -1
+}
+
+// windowFunctions are the functions the parser accepts an OVER clause for
+var windowFunctions = map[string]bool{
+	"row_number":   true,
+	"rank":         true,
+	"dense_rank":   true,
+	"percent_rank": true,
+	"cume_dist":    true,
+	"ntile":        true,
+	"lag":          true,
+	"lead":         true,
+	"first_value":  true,
+	"last_value":   true,
+	"nth_value":    true,
+}
+
+// RewriteWindowAggregates wraps function calls followed by an OVER clause,
+// which the parser only accepts for window functions, in NTH_VALUE with a
+// position of 0. NTH_VALUE counts from 1, so the wrapper cannot be mistaken
+// for a call written in the query:
+//
+//	SUM(price) OVER (ORDER BY id) => NTH_VALUE(SUM(price), 0) OVER (ORDER BY id)
+func RewriteWindowAggregates(str string) (string, error) {
+	return RewriteQuery(str, WindowAggregateEdits)
+}
+
+// WindowAggregateEdits collects the edits of RewriteWindowAggregates
+func WindowAggregateEdits(str string, tokens Tokens, edits *Edits) error {
+	for i := range tokens {
+		if !tokens.IsName(i) || tokens[i].Kind != WordToken || windowFunctions[strings.ToLower(tokens[i].Text)] {
+			continue
+		}
+		open := tokens.Next(i)
+		if !tokens.IsSymbol(open, "(") {
+			continue
+		}
+		close, err := tokens.Close(open)
+		if err != nil {
+			return err
+		}
+		if tokens.Is(tokens.Next(close), "OVER") {
+			edits.Insert(tokens[i].Start, "NTH_VALUE(")
+			edits.Insert(tokens[close].End, ", 0)")
+		}
+	}
+	return nil
+}
+
+// SymmetricBetween is the function RewriteBetween wraps the lower bound of
+// a BETWEEN SYMMETRIC expression in
+const SymmetricBetween = "genql_symmetric"
+
+// RewriteBetween rewrites the SYMMETRIC and ASYMMETRIC modifiers of BETWEEN,
+// which the parser does not know. ASYMMETRIC is the default and is dropped,
+// SYMMETRIC is carried by wrapping the lower bound (see SymmetricBound):
+//
+//	x BETWEEN SYMMETRIC 10 AND 1 => x BETWEEN genql_symmetric(10) AND 1
+func RewriteBetween(str string) (string, error) {
+	return RewriteQuery(str, BetweenEdits)
+}
+
+// BetweenEdits collects the edits of RewriteBetween
+func BetweenEdits(str string, tokens Tokens, edits *Edits) error {
+	for i := range tokens {
+		if !tokens.Is(i, "BETWEEN") {
+			continue
+		}
+		next := tokens.Next(i)
+		switch {
+		case tokens.Is(next, "ASYMMETRIC"):
+			{
+				edits.Replace(tokens[i].End, tokens[next].End, "")
+			}
+		case tokens.Is(next, "SYMMETRIC"):
+			{
+				start := tokens.Next(next)
+				and, err := BoundEnd(tokens, start)
+				if err != nil {
+					return err
+				}
+				edits.Replace(tokens[i].End, tokens[start].Start, fmt.Sprintf(" %s(", SymmetricBetween))
+				edits.Insert(tokens[tokens.Previous(and)].End, ")")
+			}
+		}
+	}
+	return nil
+}
+
+// BoundEnd returns the index of the AND that ends the lower bound of a
+// BETWEEN expression starting at the token start
+func BoundEnd(tokens Tokens, start int) (int, error) {
+	for i := start; i < len(tokens); i++ {
+		switch {
+		case tokens.IsSymbol(i, "("):
+			{
+				close, err := tokens.Close(i)
+				if err != nil {
+					return 0, err
+				}
+				i = close
+			}
+		case tokens.Is(i, "AND"):
+			{
+				if i == start {
+					break
+				}
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("expected AND after BETWEEN SYMMETRIC")
+}
+
+// AnyQuantifier and AllQuantifier are the functions RewriteQuantifiers
+// wraps the right side of a comparison with ANY or ALL in
+const (
+	AnyQuantifier = "genql_any"
+	AllQuantifier = "genql_all"
+)
+
+// RewriteQuantifiers rewrites the forms of IN, ANY and ALL the parser does
+// not know. ANY, SOME and ALL after a comparison operator become function
+// calls (see Quantifier) and IN followed by a column gets the parentheses
+// of a value list, which expands arrays:
+//
+//	a > ALL (SELECT b FROM t) => a > genql_all((SELECT b FROM t))
+//	'admin' IN roles          => 'admin' IN (roles)
+func RewriteQuantifiers(str string) (string, error) {
+	return RewriteQuery(str, QuantifierEdits)
+}
+
+// QuantifierEdits collects the edits of RewriteQuantifiers
+func QuantifierEdits(str string, tokens Tokens, edits *Edits) error {
+	for i := range tokens {
+		switch {
+		case tokens.Is(i, "ANY", "SOME", "ALL"):
+			{
+				open := tokens.Next(i)
+				if !tokens.IsSymbol(tokens.Previous(i), "=<>") || !tokens.IsSymbol(open, "(") {
+					break
+				}
+				close, err := tokens.Close(open)
+				if err != nil {
+					return err
+				}
+				quantifier := AnyQuantifier
+				if tokens.Is(i, "ALL") {
+					quantifier = AllQuantifier
+				}
+				edits.Replace(tokens[i].Start, tokens[open].Start, quantifier+"(")
+				edits.Insert(tokens[close].End, ")")
+			}
+		case tokens.Is(i, "IN"):
+			{
+				start := tokens.Next(i)
+				if !tokens.IsName(start) || tokens.Is(start, "NATURAL", "BOOLEAN") {
+					break
+				}
+				end := tokens.NameEnd(start)
+				edits.Insert(tokens[start].Start, "(")
+				edits.Insert(tokens[end-1].End, ")")
+			}
+		}
+	}
+	return nil
+}
+
+// QuotedEnd returns the index right after the quoted text starting at start
+func QuotedEnd(str string, start int) (int, error) {
+	quote := str[start]
+	for i := start + 1; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			{
+				if quote != '`' {
+					i++
+				}
+			}
+		case quote:
+			{
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated %c", quote)
+}
+
+func IsWordStart(r byte) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func IsWordPart(r byte) bool {
+	return IsWordStart(r) || (r >= '0' && r <= '9')
+}
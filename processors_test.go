@@ -137,3 +137,393 @@ func TestFixIdiomaticArray(t *testing.T) {
 		})
 	}
 }
+
+func TestRewriteJoins(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		expectErr bool
+	}{
+		{
+			name:  "Full Join",
+			input: "SELECT * FROM a x FULL JOIN b y ON x.id = y.id",
+			want:  "SELECT * FROM a x LEFT JOIN b y ON genql_full_join(x.id = y.id)",
+		},
+		{
+			name:  "Full Outer Join",
+			input: "SELECT * FROM a x full\n\touter join b y ON x.id = y.id",
+			want:  "SELECT * FROM a x LEFT JOIN b y ON genql_full_join(x.id = y.id)",
+		},
+		{
+			name:  "Straight Join",
+			input: "SELECT * FROM a x STRAIGHT_JOIN b y ON x.id = y.id",
+			want:  "SELECT * FROM a x STRAIGHT_JOIN b y ON x.id = y.id",
+		},
+		{
+			name:  "Full Join Followed By Clauses",
+			input: "SELECT * FROM a x FULL JOIN b y ON x.id = y.id OR LEFT(x.n, 1) = 'a' /* c */ STRAIGHT_JOIN c z ON TRUE WHERE x.id > 1",
+			want:  "SELECT * FROM a x LEFT JOIN b y ON genql_full_join(x.id = y.id OR LEFT(x.n, 1) = 'a') /* c */ STRAIGHT_JOIN c z ON TRUE WHERE x.id > 1",
+		},
+		{
+			name:  "Full Join Of Nested Join",
+			input: "SELECT * FROM (SELECT * FROM a x FULL JOIN (b y JOIN c z ON y.id = z.id) ON x.id = y.id) t",
+			want:  "SELECT * FROM (SELECT * FROM a x LEFT JOIN (b y JOIN c z ON y.id = z.id) ON genql_full_join(x.id = y.id)) t",
+		},
+		{
+			name:      "Full Join Without Condition",
+			input:     "SELECT * FROM a x FULL JOIN b y WHERE x.id = 1",
+			expectErr: true,
+		},
+		{
+			name:  "Cross Apply",
+			input: "SELECT * FROM parents p CROSS APPLY `p.items` i",
+			want:  "SELECT * FROM parents p JOIN LATERAL (SELECT * FROM `p.items`) i",
+		},
+		{
+			name:  "Cross Apply Derived Table",
+			input: "SELECT * FROM parents p CROSS APPLY (SELECT * FROM p.items) i",
+			want:  "SELECT * FROM parents p JOIN LATERAL (SELECT * FROM p.items) i",
+		},
+		{
+			name:  "Cross Join",
+			input: "SELECT * FROM a CROSS JOIN b",
+			want:  "SELECT * FROM a CROSS JOIN b",
+		},
+		{
+			name:  "Keywords In Comments",
+			input: "SELECT * FROM a /* FULL JOIN */ x -- CROSS APPLY t\nJOIN b y ON x.id = y.id # STRAIGHT_JOIN",
+			want:  "SELECT * FROM a /* FULL JOIN */ x -- CROSS APPLY t\nJOIN b y ON x.id = y.id # STRAIGHT_JOIN",
+		},
+		{
+			name:  "Comment Between Keywords",
+			input: "SELECT * FROM a x FULL /* outer */ OUTER -- join\n JOIN b y ON x.id = y.id",
+			want:  "SELECT * FROM a x LEFT JOIN b y ON genql_full_join(x.id = y.id)",
+		},
+		{
+			name:  "Quoted Keywords",
+			input: "SELECT 'full join', `full` AS \"cross apply\" FROM a fullness",
+			want:  "SELECT 'full join', `full` AS \"cross apply\" FROM a fullness",
+		},
+		{
+			name:      "Unterminated Quote",
+			input:     "SELECT 'full join FROM a",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RewriteJoins(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if result != tt.want {
+					t.Errorf("expected %v, got %v", tt.want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteWindowAggregates(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		expectErr bool
+	}{
+		{
+			name:  "Aggregate",
+			input: "SELECT SUM(price) OVER (ORDER BY id) FROM a",
+			want:  "SELECT NTH_VALUE(SUM(price), 0) OVER (ORDER BY id) FROM a",
+		},
+		{
+			name:  "Count Star",
+			input: "SELECT count (*)\n\tover () AS c FROM a",
+			want:  "SELECT NTH_VALUE(count (*), 0)\n\tover () AS c FROM a",
+		},
+		{
+			name:  "Nested",
+			input: "SELECT round(AVG(x) OVER w, 2) FROM a WINDOW w AS (ORDER BY id)",
+			want:  "SELECT round(NTH_VALUE(AVG(x), 0) OVER w, 2) FROM a WINDOW w AS (ORDER BY id)",
+		},
+		{
+			name:  "Window Function",
+			input: "SELECT ROW_NUMBER() OVER (), lag(x) OVER () FROM a",
+			want:  "SELECT ROW_NUMBER() OVER (), lag(x) OVER () FROM a",
+		},
+		{
+			name:  "Keywords In Comments",
+			input: "SELECT SUM(price) /* OVER () */ FROM a -- SUM(x) OVER ()",
+			want:  "SELECT SUM(price) /* OVER () */ FROM a -- SUM(x) OVER ()",
+		},
+		{
+			name:  "Comment Before Over",
+			input: "SELECT SUM(price) /* running */ OVER (ORDER BY id) FROM a",
+			want:  "SELECT NTH_VALUE(SUM(price), 0) /* running */ OVER (ORDER BY id) FROM a",
+		},
+		{
+			name:  "Without Over",
+			input: "SELECT SUM(price), overall FROM a",
+			want:  "SELECT SUM(price), overall FROM a",
+		},
+		{
+			name:  "Quoted",
+			input: "SELECT 'SUM(x) OVER ()', SUM(`a)b`) OVER () FROM a",
+			want:  "SELECT 'SUM(x) OVER ()', NTH_VALUE(SUM(`a)b`), 0) OVER () FROM a",
+		},
+		{
+			name:      "Unterminated Parenthesis",
+			input:     "SELECT SUM(price OVER () FROM a",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RewriteWindowAggregates(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if result != tt.want {
+					t.Errorf("expected %v, got %v", tt.want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteSetOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		expectErr bool
+	}{
+		{
+			name:  "Union",
+			input: "SELECT a FROM x UNION ALL SELECT a FROM y",
+			want:  "SELECT a FROM x UNION ALL SELECT a FROM y",
+		},
+		{
+			name:  "Intersect",
+			input: "SELECT a FROM x INTERSECT SELECT a FROM y",
+			want:  "SELECT a FROM x UNION SELECT /* genql:intersect */ a FROM y",
+		},
+		{
+			name:  "Except All",
+			input: "SELECT a FROM x except all SELECT a FROM y",
+			want:  "SELECT a FROM x UNION ALL SELECT /* genql:except */ a FROM y",
+		},
+		{
+			name:  "Intersect Distinct",
+			input: "SELECT a FROM x INTERSECT DISTINCT (SELECT a FROM y)",
+			want:  "SELECT a FROM x UNION (SELECT /* genql:intersect */ a FROM y)",
+		},
+		{
+			name:  "Precedence",
+			input: "SELECT a FROM x UNION SELECT a FROM y INTERSECT SELECT a FROM z EXCEPT SELECT a FROM w",
+			want:  "SELECT a FROM x UNION ( SELECT a FROM y UNION SELECT /* genql:intersect */ a FROM z ) UNION SELECT /* genql:except */ a FROM w",
+		},
+		{
+			name:  "Precedence Before Order By",
+			input: "SELECT a FROM x EXCEPT SELECT a FROM y INTERSECT SELECT a FROM z ORDER BY a",
+			want:  "SELECT a FROM x UNION ( SELECT /* genql:except */ a FROM y UNION SELECT /* genql:intersect */ a FROM z ) ORDER BY a",
+		},
+		{
+			name:  "Keywords In Comments",
+			input: "SELECT id FROM a /* x EXCEPT y */ WHERE id = 1 # INTERSECT",
+			want:  "SELECT id FROM a /* x EXCEPT y */ WHERE id = 1 # INTERSECT",
+		},
+		{
+			name:  "Select In Comment",
+			input: "SELECT a FROM x EXCEPT /* SELECT */ SELECT a FROM y",
+			want:  "SELECT a FROM x UNION /* SELECT */ SELECT /* genql:except */ a FROM y",
+		},
+		{
+			name:  "Subquery",
+			input: "SELECT * FROM (SELECT a FROM x EXCEPT SELECT a FROM y) t",
+			want:  "SELECT * FROM (SELECT a FROM x UNION SELECT /* genql:except */ a FROM y) t",
+		},
+		{
+			name:  "Quoted",
+			input: "SELECT 'a INTERSECT b', `except` FROM x",
+			want:  "SELECT 'a INTERSECT b', `except` FROM x",
+		},
+		{
+			name:      "Missing Operand",
+			input:     "SELECT a FROM x EXCEPT",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RewriteSetOperations(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if result != tt.want {
+					t.Errorf("expected %v, got %v", tt.want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteBetween(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		expectErr bool
+	}{
+		{
+			name:  "Between",
+			input: "SELECT a FROM x WHERE a BETWEEN 1 AND 10",
+			want:  "SELECT a FROM x WHERE a BETWEEN 1 AND 10",
+		},
+		{
+			name:  "Symmetric",
+			input: "SELECT a FROM x WHERE a between symmetric 10 AND 1",
+			want:  "SELECT a FROM x WHERE a between genql_symmetric(10) AND 1",
+		},
+		{
+			name:  "Asymmetric",
+			input: "SELECT a FROM x WHERE a NOT BETWEEN ASYMMETRIC 1 AND 10",
+			want:  "SELECT a FROM x WHERE a NOT BETWEEN 1 AND 10",
+		},
+		{
+			name:  "Keywords In Comments",
+			input: "SELECT a FROM x WHERE a BETWEEN /* SYMMETRIC */ 1 AND 10 -- BETWEEN SYMMETRIC 1",
+			want:  "SELECT a FROM x WHERE a BETWEEN /* SYMMETRIC */ 1 AND 10 -- BETWEEN SYMMETRIC 1",
+		},
+		{
+			name:  "And In Comment",
+			input: "SELECT a FROM x WHERE a BETWEEN SYMMETRIC 10 /* AND 5 */ AND 1",
+			want:  "SELECT a FROM x WHERE a BETWEEN genql_symmetric(10) /* AND 5 */ AND 1",
+		},
+		{
+			name:  "Expression Bound",
+			input: "SELECT a FROM x WHERE a BETWEEN SYMMETRIC (b + 1) * 2 AND c AND d = 'BETWEEN SYMMETRIC'",
+			want:  "SELECT a FROM x WHERE a BETWEEN genql_symmetric((b + 1) * 2) AND c AND d = 'BETWEEN SYMMETRIC'",
+		},
+		{
+			name:  "Window Frame",
+			input: "SELECT SUM(a) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM x",
+			want:  "SELECT SUM(a) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM x",
+		},
+		{
+			name:      "Missing And",
+			input:     "SELECT a FROM x WHERE a BETWEEN SYMMETRIC 1",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RewriteBetween(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if result != tt.want {
+					t.Errorf("expected %v, got %v", tt.want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteQuantifiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		expectErr bool
+	}{
+		{
+			name:  "Value List",
+			input: "SELECT a FROM x WHERE a IN (1, 2) AND b NOT IN (SELECT b FROM y)",
+			want:  "SELECT a FROM x WHERE a IN (1, 2) AND b NOT IN (SELECT b FROM y)",
+		},
+		{
+			name:  "Array Column",
+			input: "SELECT a FROM x WHERE 'admin' IN roles AND 'root' NOT IN `user.roles`",
+			want:  "SELECT a FROM x WHERE 'admin' IN (roles) AND 'root' NOT IN (`user.roles`)",
+		},
+		{
+			name:  "Any",
+			input: "SELECT a FROM x WHERE a = ANY (SELECT b FROM y)",
+			want:  "SELECT a FROM x WHERE a = genql_any((SELECT b FROM y))",
+		},
+		{
+			name:  "Some And All",
+			input: "SELECT a FROM x WHERE a>=some(SELECT b FROM y) OR a <> all (tags)",
+			want:  "SELECT a FROM x WHERE a>=genql_any((SELECT b FROM y)) OR a <> genql_all((tags))",
+		},
+		{
+			name:  "Keywords In Comments",
+			input: "SELECT a FROM x WHERE a = /* ANY */ (1) # 'admin' IN roles",
+			want:  "SELECT a FROM x WHERE a = /* ANY */ (1) # 'admin' IN roles",
+		},
+		{
+			name:  "Comment Before Operand",
+			input: "SELECT a FROM x WHERE a = ANY /* tags */ (tags) AND 'x' IN -- roles\n roles",
+			want:  "SELECT a FROM x WHERE a = genql_any((tags)) AND 'x' IN -- roles\n (roles)",
+		},
+		{
+			name:  "Union All",
+			input: "SELECT a FROM x UNION ALL (SELECT a FROM y)",
+			want:  "SELECT a FROM x UNION ALL (SELECT a FROM y)",
+		},
+		{
+			name:  "Quoted",
+			input: "SELECT a FROM x WHERE b = 'IN roles' AND c = '= ANY (1)'",
+			want:  "SELECT a FROM x WHERE b = 'IN roles' AND c = '= ANY (1)'",
+		},
+		{
+			name:      "Unterminated",
+			input:     "SELECT a FROM x WHERE a = ANY (SELECT b FROM y",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RewriteQuantifiers(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if result != tt.want {
+					t.Errorf("expected %v, got %v", tt.want, result)
+				}
+			}
+		})
+	}
+}
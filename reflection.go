@@ -0,0 +1,157 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type (
+	StructField struct {
+		Name  string
+		Index []int
+		Type  reflect.Type
+	}
+	structFieldsKey struct {
+		t   reflect.Type
+		tag string
+	}
+)
+
+var (
+	structFields sync.Map
+)
+
+// Normalize converts a native Go value into the representation used by the
+// engine. Structs (honouring `json` tags) and maps with string keys become
+// Map, slices and arrays become []any and pointers are dereferenced. Only
+// the outermost value is converted, nested values are converted when read.
+func Normalize(value any) any {
+	if IsNormalized(value) {
+		return value
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		{
+			if v.IsNil() {
+				return nil
+			}
+			return Normalize(v.Elem().Interface())
+		}
+	case reflect.Struct:
+		{
+			fields := StructFields(v.Type(), "json")
+			if len(fields) == 0 {
+				return value
+			}
+			mapper := make(Map, len(fields))
+			for _, field := range fields {
+				value, err := v.FieldByIndexErr(field.Index)
+				if err != nil {
+					continue
+				}
+				mapper[field.Name] = value.Interface()
+			}
+			return mapper
+		}
+	case reflect.Map:
+		{
+			if v.Type().Key().Kind() != reflect.String {
+				return value
+			}
+			if v.IsNil() {
+				return nil
+			}
+			mapper := make(Map, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				mapper[iter.Key().String()] = iter.Value().Interface()
+			}
+			return mapper
+		}
+	case reflect.Slice, reflect.Array:
+		{
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return value
+			}
+			if v.Kind() == reflect.Slice && v.IsNil() {
+				return nil
+			}
+			slice := make([]any, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				slice[i] = v.Index(i).Interface()
+			}
+			return slice
+		}
+	default:
+		{
+			return value
+		}
+	}
+}
+
+// IsNormalized reports whether a value is already in the representation
+// used by the engine, which makes it cheap to check before converting.
+func IsNormalized(value any) bool {
+	switch value.(type) {
+	case nil, Map, []any, string, float64, int, bool, func() (any, error):
+		{
+			return true
+		}
+	default:
+		{
+			return false
+		}
+	}
+}
+
+// StructFields lists the exported fields of a struct type along with the
+// names given to them by the tag. The result is cached per type and tag.
+func StructFields(t reflect.Type, tag string) []StructField {
+	key := structFieldsKey{t: t, tag: tag}
+	if fields, ok := structFields.Load(key); ok {
+		return fields.([]StructField)
+	}
+	fields := make([]StructField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, embedded := range StructFields(field.Type, tag) {
+				embedded.Index = append([]int{i}, embedded.Index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, StructField{
+			Name:  name,
+			Index: []int{i},
+			Type:  field.Type,
+		})
+	}
+	structFields.Store(key, fields)
+	return fields
+}
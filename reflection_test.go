@@ -0,0 +1,128 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type (
+	testBase struct {
+		Id int `json:"id"`
+	}
+	testItem struct {
+		Sku   string  `json:"sku"`
+		Price float64 `json:"price"`
+	}
+	testOrder struct {
+		testBase
+		Customer *string          `json:"customer,omitempty"`
+		Items    []testItem       `json:"items"`
+		Tags     []string         `json:"tags"`
+		Meta     map[string]int64 `json:"meta"`
+		Secret   string           `json:"-"`
+		internal string
+	}
+)
+
+func TestNormalize(t *testing.T) {
+	name := "John"
+	test := []struct {
+		Name     string
+		Value    any
+		Expected any
+	}{
+		{Name: "Native", Value: Map{"a": 1.0}, Expected: Map{"a": 1.0}},
+		{Name: "Struct", Value: testItem{Sku: "a", Price: 1}, Expected: Map{"sku": "a", "price": 1.0}},
+		{Name: "Pointer", Value: &testItem{Sku: "a"}, Expected: Map{"sku": "a", "price": 0.0}},
+		{Name: "NilPointer", Value: (*testItem)(nil), Expected: nil},
+		{Name: "Embedded", Value: testOrder{testBase: testBase{Id: 1}, Customer: &name, Secret: "x", internal: "y"}, Expected: Map{"id": 1, "customer": &name, "items": []testItem(nil), "tags": []string(nil), "meta": map[string]int64(nil)}},
+		{Name: "TypedMap", Value: map[string]int{"a": 1}, Expected: Map{"a": 1}},
+		{Name: "NonStringKeys", Value: map[int]int{1: 1}, Expected: map[int]int{1: 1}},
+		{Name: "TypedSlice", Value: []int{1, 2}, Expected: []any{1, 2}},
+		{Name: "Array", Value: [2]string{"a", "b"}, Expected: []any{"a", "b"}},
+		{Name: "Bytes", Value: []byte("a"), Expected: []byte("a")},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			rs := Normalize(test.Value)
+			if !reflect.DeepEqual(rs, test.Expected) {
+				t.Fatalf("expected %v but found %v", test.Expected, rs)
+			}
+		})
+	}
+}
+
+func TestQuery_NativeValues(t *testing.T) {
+	customer := "John"
+	data := struct {
+		Orders []*testOrder `json:"orders"`
+	}{
+		Orders: []*testOrder{
+			{testBase: testBase{Id: 1}, Customer: &customer, Items: []testItem{{Sku: "a", Price: 10}, {Sku: "b", Price: 5}}, Tags: []string{"new"}, Meta: map[string]int64{"priority": 2}},
+			{testBase: testBase{Id: 2}, Items: []testItem{{Sku: "c", Price: 1}}},
+		},
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	document := make(Map)
+	err = json.Unmarshal(raw, &document)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	test := []struct {
+		Name  string
+		Query string
+	}{
+		{Name: "Fields", Query: "SELECT id, customer FROM `root.orders`"},
+		{Name: "Nested", Query: "SELECT id, `items.sku` AS skus, `meta.priority` AS priority FROM `root.orders`"},
+		{Name: "Functions", Query: "SELECT id, FIRST(tags) AS tag, CHANGETYPE(id, 'string') AS code FROM `root.orders`"},
+		{Name: "Where", Query: "SELECT id FROM `root.orders` WHERE id = 2"},
+		{Name: "Items", Query: "SELECT sku, price FROM `root.orders[0].items` ORDER BY price"},
+		{Name: "Aggregate", Query: "SELECT SUM(price) AS total FROM `root.orders[0].items`"},
+		{Name: "Arithmetic", Query: "SELECT id + 1 AS following, -id AS negative, id * `meta.priority` AS weight, id DIV 2 AS half FROM `root.orders`"},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			query, err := New(document, test.Query, Wrapped())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			expected, err := query.Exec()
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			query, err = New(data, test.Query, Wrapped())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			rs, err := query.Exec()
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if fmt.Sprintf("%v", rs) != fmt.Sprintf("%v", expected) {
+				t.Fatalf("expected %v but found %v", expected, rs)
+			}
+		})
+	}
+	_, err = New([]int{1}, "SELECT * FROM `root`")
+	if err == nil {
+		t.Fatalf("expected an error when binding a non-object without wrapping")
+	}
+}
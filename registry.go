@@ -0,0 +1,162 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"strings"
+	"sync"
+)
+
+// Registry holds the functions a query can call. Queries use the default
+// registry unless one is given through WithRegistry. A registry is safe for
+// concurrent use, so functions can be registered while queries are running.
+type Registry struct {
+	mut                sync.RWMutex
+	functions          map[string]Function
+	immediateFunctions map[string]bool
+	lazyFunctions      map[string]bool
+	topLevelFunctions  TopLevelFunction
+}
+
+var (
+	defaultRegistry = newRegistry()
+)
+
+func newRegistry() *Registry {
+	return &Registry{
+		functions:          make(map[string]Function),
+		immediateFunctions: make(map[string]bool),
+		lazyFunctions:      make(map[string]bool),
+		topLevelFunctions:  make(TopLevelFunction),
+	}
+}
+
+// NewRegistry creates a registry that holds the built-in functions only
+func NewRegistry() *Registry {
+	registry := newRegistry()
+	RegisterBuiltInFunctions(registry)
+	return registry
+}
+
+// DefaultRegistry returns the registry the package level Register functions
+// write to
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+func WithRegistry(registry *Registry) QueryOption {
+	return func(query *Query) {
+		query.options.registry = registry
+	}
+}
+
+// Registry returns the registry the query calls functions from
+func (query *Query) Registry() *Registry {
+	if query.options == nil || query.options.registry == nil {
+		return defaultRegistry
+	}
+	return query.options.registry
+}
+
+// Clone copies the registry so it can be extended without affecting the
+// queries using the original
+func (registry *Registry) Clone() *Registry {
+	registry.mut.RLock()
+	defer registry.mut.RUnlock()
+	clone := newRegistry()
+	for name, function := range registry.functions {
+		clone.functions[name] = function
+	}
+	for name := range registry.immediateFunctions {
+		clone.immediateFunctions[name] = true
+	}
+	for name := range registry.lazyFunctions {
+		clone.lazyFunctions[name] = true
+	}
+	for name, function := range registry.topLevelFunctions {
+		clone.topLevelFunctions[name] = function
+	}
+	return clone
+}
+
+func (registry *Registry) RegisterFunction(name string, function Function) {
+	registry.mut.Lock()
+	defer registry.mut.Unlock()
+	registry.functions[strings.ToLower(name)] = function
+	delete(registry.immediateFunctions, strings.ToLower(name))
+	delete(registry.lazyFunctions, strings.ToLower(name))
+}
+
+func (registry *Registry) RegisterImmediateFunction(name string, function Function) {
+	registry.mut.Lock()
+	defer registry.mut.Unlock()
+	registry.functions[strings.ToLower(name)] = function
+	registry.immediateFunctions[strings.ToLower(name)] = true
+	delete(registry.lazyFunctions, strings.ToLower(name))
+}
+
+// RegisterLazyFunction registers a function that receives its arguments as
+// thunks. An argument is only evaluated when the function forces it, which
+// lets functions such as IF and COALESCE skip the branches they do not use.
+func (registry *Registry) RegisterLazyFunction(name string, function Function) {
+	registry.mut.Lock()
+	defer registry.mut.Unlock()
+	registry.functions[strings.ToLower(name)] = function
+	registry.lazyFunctions[strings.ToLower(name)] = true
+	delete(registry.immediateFunctions, strings.ToLower(name))
+}
+
+func (registry *Registry) RegisterExternalFunction(name string, function func([]any) (any, error)) {
+	registry.RegisterFunction(name, func(_ *Query, _ Map, _ *FunctionOptions, args []any) (any, error) {
+		return function(args)
+	})
+}
+
+func (registry *Registry) Import(functions map[string]func([]any) (any, error)) {
+	for name, function := range functions {
+		registry.RegisterExternalFunction(name, function)
+	}
+}
+
+func (registry *Registry) RegisterTopLevelFunction(name string, function func(any) (any, error)) {
+	registry.mut.Lock()
+	defer registry.mut.Unlock()
+	registry.topLevelFunctions[name] = function
+}
+
+func (registry *Registry) Function(name string) (Function, bool) {
+	registry.mut.RLock()
+	defer registry.mut.RUnlock()
+	function, ok := registry.functions[strings.ToLower(name)]
+	return function, ok
+}
+
+func (registry *Registry) IsImmediateFunction(name string) bool {
+	registry.mut.RLock()
+	defer registry.mut.RUnlock()
+	return registry.immediateFunctions[strings.ToLower(name)]
+}
+
+func (registry *Registry) IsLazyFunction(name string) bool {
+	registry.mut.RLock()
+	defer registry.mut.RUnlock()
+	return registry.lazyFunctions[strings.ToLower(name)]
+}
+
+func (registry *Registry) TopLevelFunction(name string) (func(any) (any, error), bool) {
+	registry.mut.RLock()
+	defer registry.mut.RUnlock()
+	function, ok := registry.topLevelFunctions[name]
+	return function, ok
+}
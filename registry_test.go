@@ -0,0 +1,121 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	RegisterFunction("test_registry_global", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		return "global", nil
+	})
+	tenantA := NewRegistry()
+	tenantA.RegisterFunction("tenant", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		return "a", nil
+	})
+	tenantA.RegisterTopLevelFunction("first_only", func(data any) (any, error) {
+		array, err := AsArray(data)
+		if err != nil || len(array) == 0 {
+			return nil, err
+		}
+		return array[0], nil
+	})
+	tenantB := tenantA.Clone()
+	tenantB.Import(map[string]func([]any) (any, error){
+		"tenant": func(a []any) (any, error) {
+			return "b", nil
+		},
+	})
+	data := Map{
+		"items": []any{Map{"id": 1.0, "tags": []any{"x", "y"}}},
+	}
+	test := []struct {
+		Name     string
+		Query    string
+		Registry *Registry
+		Expected string
+		Error    error
+	}{
+		{Name: "Tenant A", Query: "SELECT tenant() AS t FROM `root.items`", Registry: tenantA, Expected: "[map[t:a]]"},
+		{Name: "Tenant B", Query: "SELECT tenant() AS t FROM `root.items`", Registry: tenantB, Expected: "[map[t:b]]"},
+		{Name: "Built-In", Query: "SELECT to_upper(`tags[0]`) AS t FROM `root.items`", Registry: tenantB, Expected: "[map[t:X]]"},
+		{Name: "Top Level", Query: "SELECT `first_only=>tags` AS t FROM `root.items`", Registry: tenantA, Expected: "[map[t:x]]"},
+		{Name: "Isolated", Query: "SELECT tenant() AS t FROM `root.items`", Registry: nil, Error: INVALID_FUNCTION},
+		{Name: "Isolated Global", Query: "SELECT test_registry_global() AS t FROM `root.items`", Registry: tenantA, Error: INVALID_FUNCTION},
+		{Name: "Default", Query: "SELECT test_registry_global() AS t FROM `root.items`", Registry: nil, Expected: "[map[t:global]]"},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			options := []QueryOption{Wrapped()}
+			if test.Registry != nil {
+				options = append(options, WithRegistry(test.Registry))
+			}
+			query, err := New(data, test.Query, options...)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			rs, err := query.Exec()
+			if test.Error != nil {
+				if !errors.Is(err, test.Error) {
+					t.Fatalf("expected %v but found %v", test.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if out := fmt.Sprintf("%v", rs); out != test.Expected {
+				t.Fatalf("expected %s but found %s", test.Expected, out)
+			}
+		})
+	}
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterFunction("value", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		return 1.0, nil
+	})
+	plan, err := Compile("SELECT value() AS v FROM `root.items`", Wrapped(), WithRegistry(registry))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	data := Map{"items": []any{Map{"id": 1.0}, Map{"id": 2.0}}}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			registry.RegisterFunction(fmt.Sprintf("value_%d", i), func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+				return nil, nil
+			})
+		}(i)
+		go func() {
+			defer wg.Done()
+			rs, err := plan.Exec(data)
+			if err != nil {
+				t.Errorf("%v", err)
+				return
+			}
+			if len(rs) != 2 {
+				t.Errorf("expected 2 rows but found %d", len(rs))
+			}
+		}()
+	}
+	wg.Wait()
+}
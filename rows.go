@@ -0,0 +1,219 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"context"
+)
+
+// Rows iterates over the result of a query. Rows are filtered and
+// projected one at a time unless the query needs to see the whole result
+// set (GROUP BY, ORDER BY, DISTINCT and select-all aggregates), in which
+// case the result is buffered first.
+type Rows struct {
+	query     *Query
+	streaming bool
+	buffer    []any
+	index     int
+	skipped   int
+	count     int
+	current   any
+	err       error
+	closed    bool
+	cancel    context.CancelFunc
+}
+
+func (query *Query) Rows(params ...any) (*Rows, error) {
+	return query.RowsContext(context.Background(), params...)
+}
+
+func (query *Query) RowsContext(ctx context.Context, params ...any) (*Rows, error) {
+	run, cancel := query.start(ctx, params)
+	rows := &Rows{
+		query:  run,
+		cancel: cancel,
+	}
+	if run.explain {
+		cancel()
+		rows.buffer = []any{run.Explain().Map()}
+		return rows, nil
+	}
+	err := ExecFrom(run)
+	if err != nil {
+		cancel()
+		return nil, run.timeoutErr(err)
+	}
+	if IsStreamable(run) {
+		rows.streaming = true
+		return rows, nil
+	}
+	defer cancel()
+	rs, err := run.execAndPostProcess()
+	if err != nil {
+		return nil, run.timeoutErr(err)
+	}
+	slice, ok := rs.([]any)
+	if !ok {
+		slice = []any{rs}
+	}
+	err = CheckResultRows(run, len(slice))
+	if err != nil {
+		return nil, err
+	}
+	rows.buffer = slice
+	return rows, nil
+}
+
+// IsStreamable reports whether the rows of a query can be produced one by
+// one without looking at the rest of the result set
+func IsStreamable(query *Query) bool {
+	if query.dual || query.distinct || query.dmlDefinition != nil {
+		return false
+	}
+	if len(query.groupDefinition) != 0 || len(query.orderByDefinition) != 0 || len(query.windowDefinitions) != 0 {
+		return false
+	}
+	return !IsSelectAllAggregate(query)
+}
+
+func (rows *Rows) Next() bool {
+	if rows.closed || rows.err != nil {
+		return false
+	}
+	if !rows.streaming {
+		if rows.index >= len(rows.buffer) {
+			rows.current = nil
+			return false
+		}
+		rows.current = rows.buffer[rows.index]
+		rows.index++
+		return true
+	}
+	row, ok, err := rows.next()
+	if err == nil && ok {
+		err = CheckResultRows(rows.query, rows.count)
+	}
+	if err != nil {
+		rows.current = nil
+		rows.err = rows.query.timeoutErr(err)
+		rows.cancel()
+		return false
+	}
+	if !ok {
+		rows.current = nil
+		rows.closed = true
+		rows.cancel()
+		if rows.query.options.completed != nil {
+			rows.query.options.completed()
+		}
+		return false
+	}
+	rows.current = row
+	return true
+}
+
+func (rows *Rows) next() (row any, ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(error)
+		}
+	}()
+	query := rows.query
+	for {
+		if query.limitDefinition != -1 && rows.count >= query.limitDefinition {
+			return nil, false, nil
+		}
+		if rows.index >= len(query.from) {
+			return nil, false, nil
+		}
+		if err := query.Context().Err(); err != nil {
+			return nil, false, err
+		}
+		current := query.from[rows.index]
+		rows.index++
+		switch current := current.(type) {
+		case []any:
+			{
+				if rows.skip() {
+					continue
+				}
+				copy := CopyQuery(query)
+				copy.from = current
+				rs, err := copy.execAndPostProcess()
+				if err != nil {
+					return nil, false, err
+				}
+				row = rs
+			}
+		case Map:
+			{
+				isMatch, err := ExecWhere(query, current)
+				if err != nil {
+					return nil, false, err
+				}
+				if !isMatch || rows.skip() {
+					continue
+				}
+				rs, err := ExecSelect(query, []any{current})
+				if err != nil {
+					return nil, false, err
+				}
+				err = query.wait()
+				if err != nil {
+					return nil, false, err
+				}
+				for _, postProcessor := range query.postProcessors {
+					err := postProcessor()
+					if err != nil {
+						return nil, false, err
+					}
+				}
+				query.postProcessors = query.postProcessors[:0]
+				row = rs[0]
+			}
+		default:
+			{
+				continue
+			}
+		}
+		rows.count++
+		return row, true, nil
+	}
+}
+
+// skip consumes the OFFSET clause
+func (rows *Rows) skip() bool {
+	if rows.skipped < rows.query.offsetDefinition {
+		rows.skipped++
+		return true
+	}
+	return false
+}
+
+// Row returns the row the last call to Next advanced to
+func (rows *Rows) Row() any {
+	return rows.current
+}
+
+func (rows *Rows) Err() error {
+	return rows.err
+}
+
+func (rows *Rows) Close() error {
+	rows.cancel()
+	rows.closed = true
+	rows.current = nil
+	rows.buffer = nil
+	return nil
+}
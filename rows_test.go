@@ -0,0 +1,152 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "John", "role": "admin"},
+			Map{"id": 2.0, "name": "Jane", "role": "user"},
+			Map{"id": 3.0, "name": "Jack", "role": "user"},
+			Map{"id": 4.0, "name": "Jill", "role": "admin"},
+		},
+	}
+	tests := []struct {
+		name      string
+		query     string
+		streaming bool
+	}{
+		{
+			name:      "Select All",
+			query:     "SELECT * FROM users",
+			streaming: true,
+		},
+		{
+			name:      "Where And Projection",
+			query:     "SELECT name FROM users WHERE role = 'user'",
+			streaming: true,
+		},
+		{
+			name:      "Limit And Offset",
+			query:     "SELECT id FROM users LIMIT 1, 2",
+			streaming: true,
+		},
+		{
+			name:      "Order By",
+			query:     "SELECT id FROM users ORDER BY id DESC",
+			streaming: false,
+		},
+		{
+			name:      "Group By",
+			query:     "SELECT role, COUNT(*) AS count FROM users GROUP BY role ORDER BY role",
+			streaming: false,
+		},
+		{
+			name:      "Distinct",
+			query:     "SELECT DISTINCT role FROM users",
+			streaming: false,
+		},
+		{
+			name:      "Aggregates",
+			query:     "SELECT COUNT(*) AS count FROM users",
+			streaming: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			want, err := q.Exec()
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			rows, err := q.Rows()
+			if err != nil {
+				t.Fatalf("Rows() error = %v", err)
+			}
+			defer rows.Close()
+			if rows.streaming != tt.streaming {
+				t.Errorf("Rows() streaming = %v, want %v", rows.streaming, tt.streaming)
+			}
+			got := make([]any, 0)
+			for rows.Next() {
+				got = append(got, rows.Row())
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("Err() = %v", err)
+			}
+			if fmt.Sprintf("%v", got) != fmt.Sprintf("%v", want) {
+				t.Errorf("Rows() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRows_Lazy(t *testing.T) {
+	calls := 0
+	RegisterFunction("test_count_calls", func(query *Query, current Map, functionOptions *FunctionOptions, args []any) (any, error) {
+		calls++
+		return calls, nil
+	})
+	q, err := New(Map{"items": []any{Map{"id": 1}, Map{"id": 2}, Map{"id": 3}}}, "SELECT test_count_calls() AS calls FROM items")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	rows, err := q.Rows()
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("Next() = false, want true")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 projected row but found %d", calls)
+	}
+	rows.Close()
+	if rows.Next() {
+		t.Errorf("Next() = true after Close()")
+	}
+}
+
+func TestRows_Cancelled(t *testing.T) {
+	q, err := New(Map{"items": []any{Map{"id": 1}, Map{"id": 2}}}, "SELECT id FROM items")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := q.RowsContext(ctx)
+	if err != nil {
+		t.Fatalf("RowsContext() error = %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("Next() = false, want true")
+	}
+	cancel()
+	if rows.Next() {
+		t.Errorf("Next() = true after cancellation")
+	}
+	if !errors.Is(rows.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want %v", rows.Err(), context.Canceled)
+	}
+}
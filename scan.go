@@ -0,0 +1,225 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ExecInto executes the query and scans the result into dest, which must be
+// a pointer to a slice or to a single value. Struct fields are mapped using
+// the `genql` tag, which accepts any selector (e.g. `genql:"address.city"`).
+// Fields without a tag are matched by name.
+func (query *Query) ExecInto(dest any, params ...any) error {
+	rs, err := query.Exec(params...)
+	if err != nil {
+		return err
+	}
+	return Scan(query, rs, dest)
+}
+
+func ExecAs[T any](query *Query, params ...any) ([]T, error) {
+	slice := make([]T, 0)
+	err := query.ExecInto(&slice, params...)
+	if err != nil {
+		return nil, err
+	}
+	return slice, nil
+}
+
+func Scan(query *Query, rows []any, dest any) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return INVALID_TYPE.Describe(fmt.Sprintf("failed to scan rows. expected a non-nil pointer but found %T", dest))
+	}
+	value = value.Elem()
+	if value.Kind() != reflect.Slice {
+		if len(rows) == 0 {
+			return KEY_NOT_FOUND.Describe("failed to scan rows. the result set is empty")
+		}
+		return ScanRow(query, 0, rows[0], value)
+	}
+	slice := reflect.MakeSlice(value.Type(), len(rows), len(rows))
+	for index, row := range rows {
+		err := ScanRow(query, index, row, slice.Index(index))
+		if err != nil {
+			return err
+		}
+	}
+	value.Set(slice)
+	return nil
+}
+
+func ScanRow(query *Query, index int, row any, dest reflect.Value) error {
+	err := ScanValue(query, row, dest)
+	if err != nil {
+		rs := INVALID_CAST.Describe(fmt.Sprintf("failed to scan row %d. %s", index, err.Error()))
+		rs.Row = index
+		return rs
+	}
+	return nil
+}
+
+func ScanValue(query *Query, value any, dest reflect.Value) error {
+	if value == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Pointer {
+		if dest.IsNil() {
+			dest.Set(reflect.New(dest.Type().Elem()))
+		}
+		return ScanValue(query, value, dest.Elem())
+	}
+	if rv := reflect.ValueOf(value); rv.Type().AssignableTo(dest.Type()) {
+		dest.Set(rv)
+		return nil
+	}
+	switch dest.Kind() {
+	case reflect.Struct:
+		{
+			row, ok := value.(Map)
+			if !ok {
+				return fmt.Errorf("expected an object but found %T", value)
+			}
+			return ScanStruct(query, row, dest)
+		}
+	case reflect.Slice:
+		{
+			array, ok := value.([]any)
+			if !ok {
+				rs, err := ChangeTypeFunc(query, nil, nil, []any{value, "array"})
+				if err != nil {
+					return err
+				}
+				array = rs.([]any)
+			}
+			slice := reflect.MakeSlice(dest.Type(), len(array), len(array))
+			for index, item := range array {
+				err := ScanValue(query, item, slice.Index(index))
+				if err != nil {
+					return fmt.Errorf("index %d: %w", index, err)
+				}
+			}
+			dest.Set(slice)
+			return nil
+		}
+	case reflect.Map:
+		{
+			row, ok := value.(Map)
+			if !ok || dest.Type().Key().Kind() != reflect.String {
+				return fmt.Errorf("cannot convert %T to %s", value, dest.Type())
+			}
+			mapper := reflect.MakeMapWithSize(dest.Type(), len(row))
+			for key, item := range row {
+				element := reflect.New(dest.Type().Elem()).Elem()
+				err := ScanValue(query, item, element)
+				if err != nil {
+					return fmt.Errorf("key %s: %w", key, err)
+				}
+				mapper.SetMapIndex(reflect.ValueOf(key).Convert(dest.Type().Key()), element)
+			}
+			dest.Set(mapper)
+			return nil
+		}
+	case reflect.String:
+		{
+			rs, err := ChangeTypeFunc(query, nil, nil, []any{value, "string"})
+			if err != nil {
+				return err
+			}
+			dest.SetString(rs.(string))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		{
+			rs, err := ChangeTypeFunc(query, nil, nil, []any{value, "double"})
+			if err != nil {
+				return err
+			}
+			dest.SetFloat(rs.(float64))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		{
+			rs, err := ChangeTypeFunc(query, nil, nil, []any{value, "integer"})
+			if err != nil {
+				return err
+			}
+			if dest.OverflowInt(int64(rs.(int))) {
+				return fmt.Errorf("%v overflows %s", value, dest.Type())
+			}
+			dest.SetInt(int64(rs.(int)))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		{
+			rs, err := ChangeTypeFunc(query, nil, nil, []any{value, "integer"})
+			if err != nil {
+				return err
+			}
+			if rs.(int) < 0 || dest.OverflowUint(uint64(rs.(int))) {
+				return fmt.Errorf("%v overflows %s", value, dest.Type())
+			}
+			dest.SetUint(uint64(rs.(int)))
+			return nil
+		}
+	case reflect.Bool:
+		{
+			rs, err := AsType[bool](value)
+			if err != nil {
+				return fmt.Errorf("cannot convert %T to bool", value)
+			}
+			dest.SetBool(*rs)
+			return nil
+		}
+	default:
+		{
+			return fmt.Errorf("cannot convert %T to %s", value, dest.Type())
+		}
+	}
+}
+
+func ScanStruct(query *Query, row Map, dest reflect.Value) error {
+	for _, field := range StructFields(dest.Type(), "genql") {
+		value, err := ReadField(query, row, field)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		err = ScanValue(query, value, dest.FieldByIndex(field.Index))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// ReadField reads the value of a struct field from a row. Tagged fields
+// are selectors and untagged fields fall back to a case insensitive match.
+func ReadField(query *Query, row Map, field StructField) (any, error) {
+	if value, ok := row[field.Name]; ok {
+		return value, nil
+	}
+	if strings.ContainsAny(field.Name, ".[{:'") {
+		return query.Registry().ExecReader(row, field.Name)
+	}
+	for key, value := range row {
+		if strings.EqualFold(key, field.Name) {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
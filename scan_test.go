@@ -0,0 +1,107 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type (
+	testAddress struct {
+		City string
+		Zip  int `genql:"zip_code"`
+	}
+	testUser struct {
+		Id      int
+		Name    string `genql:"name"`
+		Score   float64
+		City    string `genql:"address.city"`
+		Address *testAddress
+		Tags    []string
+		Ignored string `genql:"-"`
+	}
+)
+
+func TestExecAs(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "John", "score": "9.5", "address": Map{"city": "Tehran", "zip_code": "1234"}, "tags": []any{"a", 1}, "ignored": "x"},
+			Map{"id": "2", "name": "Jane", "score": 7, "address": nil, "tags": "b"},
+		},
+	}
+	query, err := New(data, "SELECT * FROM `root.users`", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs, err := ExecAs[testUser](query)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := []testUser{
+		{Id: 1, Name: "John", Score: 9.5, City: "Tehran", Address: &testAddress{City: "Tehran", Zip: 1234}, Tags: []string{"a", "1"}},
+		{Id: 2, Name: "Jane", Score: 7, Tags: []string{"b"}},
+	}
+	if !reflect.DeepEqual(rs, expected) {
+		t.Fatalf("expected %v but found %v", expected, rs)
+	}
+}
+
+func TestExecInto(t *testing.T) {
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "name": "John"},
+			Map{"id": 2.5, "name": "Jane"},
+		},
+	}
+	test := []struct {
+		Name  string
+		Query string
+		Dest  func() any
+		Out   string
+		Error string
+	}{
+		{Name: "Single", Query: "SELECT * FROM `root.users` LIMIT 1", Dest: func() any { return &testUser{} }, Out: "&{1 John 0  <nil> [] }"},
+		{Name: "Pointers", Query: "SELECT name FROM `root.users`", Dest: func() any { return &[]*testUser{} }, Out: "&[0x"},
+		{Name: "Values", Query: "SELECT name FROM `root.users`", Dest: func() any { return &[]int{} }, Error: "row 0"},
+		{Name: "Maps", Query: "SELECT name FROM `root.users`", Dest: func() any { return &[]map[string]string{} }, Out: "&[map[name:John] map[name:Jane]]"},
+		{Name: "Field", Query: "SELECT * FROM `root.users`", Dest: func() any { return &[]testUser{} }, Error: "row 1. field Id"},
+		{Name: "NotPointer", Query: "SELECT * FROM `root.users`", Dest: func() any { return []testUser{} }, Error: "non-nil pointer"},
+		{Name: "Empty", Query: "SELECT * FROM `root.users` WHERE id = 3", Dest: func() any { return &testUser{} }, Error: "empty"},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			query, err := New(data, test.Query, Wrapped())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			dest := test.Dest()
+			err = query.ExecInto(dest)
+			if test.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Error) {
+					t.Fatalf("expected error containing %q but found %v", test.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if out := fmt.Sprintf("%v", dest); !strings.HasPrefix(out, test.Out) {
+				t.Fatalf("expected %s but found %s", test.Out, out)
+			}
+		})
+	}
+}
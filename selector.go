@@ -90,18 +90,15 @@ const (
 )
 
 var (
-	fullPattern       *regexp.Regexp
-	arrayPattern      *regexp.Regexp
-	pipePattern       *regexp.Regexp
-	topLevelFunctions TopLevelFunction
+	fullPattern  *regexp.Regexp
+	arrayPattern *regexp.Regexp
+	pipePattern  *regexp.Regexp
 )
 
 func init() {
 	fullPattern = regexp.MustCompile(_FULLPATTERN)
 	arrayPattern = regexp.MustCompile(_ARRAYPATTERN)
 	pipePattern = regexp.MustCompile(_PIPEPATTERN)
-	RegisterTopLevelFunction("mix", Mix)
-	RegisterTopLevelFunction("distinct", Distinct)
 }
 
 func NewIndex[T int | [2]int](value T) *IndexSelector {
@@ -174,7 +171,7 @@ func ReadIndex(match string) (int, error) {
 		return 0, err
 	}
 	if index < 0 {
-		return 0, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to read index. invalid index %d", index))
+		return 0, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to read index. invalid index %d", index))
 	}
 	return index, nil
 }
@@ -185,7 +182,7 @@ func ReadRange(match string) (*IndexSelector, error) {
 	str = strings.TrimRight(str, string(_RPAR))
 	split := strings.Split(str, string(_COL))
 	if len(split) != 2 {
-		return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to read range. invalid range %s", match))
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to read range. invalid range %s", match))
 	}
 	rangeSelector := [2]int{}
 	switch split[0] {
@@ -280,7 +277,7 @@ func ParsePipe(match string) ([]*PipeSelector, error) {
 			slice = append(slice, NewPipe(key, split[1]))
 			continue
 		}
-		return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to parse pipe. invalid pipe %s", match))
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to parse pipe. invalid pipe %s", match))
 	}
 	return slice, nil
 }
@@ -401,16 +398,22 @@ func SelectObject(data map[string]any, key string) any {
 }
 
 func ExecReader(data any, selector string) (any, error) {
+	return defaultRegistry.ExecReader(data, selector)
+}
+
+// ExecReader reads a selector, resolving top level functions from the
+// registry
+func (registry *Registry) ExecReader(data any, selector string) (any, error) {
 	selectors := strings.Split(selector, "::")
 	result := data
 	for _, item := range selectors {
 		selectors, err := ParseSelector(item)
 		if err != nil {
-			return nil, err
+			return nil, AnnotateSelector(err, selector)
 		}
-		rs, err := ReaderExecutor(result, selectors)
+		rs, err := registry.ReaderExecutor(result, selectors)
 		if err != nil {
-			return nil, err
+			return nil, AnnotateSelector(err, selector)
 		}
 		result = rs
 	}
@@ -418,6 +421,10 @@ func ExecReader(data any, selector string) (any, error) {
 }
 
 func ReaderExecutor(data any, selectors []any) (any, error) {
+	return defaultRegistry.ReaderExecutor(data, selectors)
+}
+
+func (registry *Registry) ReaderExecutor(data any, selectors []any) (any, error) {
 	if len(selectors) == 0 {
 		return data, nil
 	}
@@ -429,9 +436,9 @@ func ReaderExecutor(data any, selectors []any) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	function, ok := topLevelFunctions[string(functionName)]
+	function, ok := registry.TopLevelFunction(string(functionName))
 	if !ok {
-		return nil, INVALID_FUNCTION.Extend(fmt.Sprintf("failed to execute function. %s is not a function", functionName))
+		return nil, INVALID_FUNCTION.Describe(fmt.Sprintf("failed to execute function. %s is not a function", functionName))
 	}
 	return function(rs)
 }
@@ -440,6 +447,7 @@ func Reader(data any, selectors []any) (any, error) {
 	if len(selectors) == 0 {
 		return data, nil
 	}
+	data = Normalize(data)
 	if data == nil {
 		return nil, nil
 	}
@@ -476,7 +484,7 @@ func Reader(data any, selectors []any) (any, error) {
 				}
 			default:
 				{
-					return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to execute read operation. key selectors are not valid on %T type", data))
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to execute read operation. key selectors are not valid on %T type", data))
 				}
 			}
 
@@ -502,7 +510,7 @@ func Reader(data any, selectors []any) (any, error) {
 				}
 			default:
 				{
-					return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to execute read operation. index selectors are not valid on %T type", data))
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to execute read operation. index selectors are not valid on %T type", data))
 				}
 			}
 
@@ -528,7 +536,7 @@ func Reader(data any, selectors []any) (any, error) {
 				}
 			default:
 				{
-					return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to execute read operation. dimension selectors are not valid on %T type", data))
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to execute read operation. dimension selectors are not valid on %T type", data))
 				}
 			}
 		}
@@ -568,7 +576,7 @@ func Reader(data any, selectors []any) (any, error) {
 							{
 								str, ok := data[selector.GetKey()].(string)
 								if !ok {
-									return nil, INVALID_TYPE.Extend(fmt.Sprintf("failed to execute pipe operation. %s is of %T type", selector.GetKey(), data[selector.GetKey()]))
+									return nil, INVALID_TYPE.Describe(fmt.Sprintf("failed to execute pipe operation. %s is of %T type", selector.GetKey(), data[selector.GetKey()]))
 								}
 								number, err := strconv.ParseFloat(str, 64)
 								if err != nil {
@@ -606,7 +614,7 @@ func Reader(data any, selectors []any) (any, error) {
 				}
 			default:
 				{
-					return nil, EXPECTATION_FAILED.Extend(fmt.Sprintf("failed to execute read operation. pipe selectors are not valid on %T type", data))
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("failed to execute read operation. pipe selectors are not valid on %T type", data))
 				}
 			}
 
@@ -694,8 +702,5 @@ func Distinct(data any) (any, error) {
 }
 
 func RegisterTopLevelFunction(name string, function func(any) (any, error)) {
-	if topLevelFunctions == nil {
-		topLevelFunctions = make(TopLevelFunction)
-	}
-	topLevelFunctions[name] = function
+	defaultRegistry.RegisterTopLevelFunction(name, function)
 }
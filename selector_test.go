@@ -786,18 +786,16 @@ func TestRegisterTopLevelFunction(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Reset topLevelFunctions before each test
-			topLevelFunctions = nil
-
 			RegisterTopLevelFunction(tt.funcName, tt.function)
 
 			// Verify function was registered
-			if _, exists := topLevelFunctions[tt.funcName]; !exists {
-				t.Errorf("function %s was not registered", tt.funcName)
+			function, exists := DefaultRegistry().TopLevelFunction(tt.funcName)
+			if !exists {
+				t.Fatalf("function %s was not registered", tt.funcName)
 			}
 
 			// Verify function works as expected
-			result, err := topLevelFunctions[tt.funcName]("test")
+			result, err := function("test")
 			if err != nil {
 				t.Errorf("expected no error, got %v", err)
 			}
@@ -13,18 +13,163 @@
 
 package genql
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
 
 type SQLError string
 
+// Error describes where a query failed. Code is one of the SQLError
+// sentinels, so errors.Is(err, KEY_NOT_FOUND) keeps working, and Err is set
+// when the failure was caused by an error from outside the engine such as
+// a custom function or a cancelled context. Row is -1 when the failure is
+// not tied to a row.
+type Error struct {
+	Code       SQLError
+	Message    string
+	Clause     string
+	Expression string
+	Row        int
+	Selector   string
+	Err        error
+}
+
 func (sqlError SQLError) Error() string {
 	return string(sqlError)
 }
 
+// Extend appends a message to the sentinel. The result still matches the
+// sentinel with errors.Is. Use Describe for an error that can be annotated
+// with the clause, expression and row it occurred in.
 func (sqlError SQLError) Extend(message string) SQLError {
 	return SQLError(fmt.Sprintf("%s. %s", sqlError, message))
 }
 
+// Is reports whether the error is the target sentinel or was extended from
+// it
+func (sqlError SQLError) Is(target error) bool {
+	code, ok := target.(SQLError)
+	return ok && strings.HasPrefix(string(sqlError), string(code)+". ")
+}
+
+// Describe returns an *Error with the sentinel as its code and the message
+// describing the failure
+func (sqlError SQLError) Describe(message string) *Error {
+	return &Error{
+		Code:    sqlError,
+		Message: message,
+		Row:     -1,
+	}
+}
+
+func (err *Error) Error() string {
+	builder := strings.Builder{}
+	switch {
+	case err.Code != "":
+		{
+			builder.WriteString(string(err.Code))
+		}
+	case err.Err != nil:
+		{
+			builder.WriteString(err.Err.Error())
+		}
+	}
+	if err.Message != "" {
+		builder.WriteString(". ")
+		builder.WriteString(err.Message)
+	}
+	context := make([]string, 0)
+	if err.Clause != "" {
+		context = append(context, fmt.Sprintf("clause %s", err.Clause))
+	}
+	if err.Expression != "" {
+		context = append(context, fmt.Sprintf("expression `%s`", err.Expression))
+	}
+	if err.Row >= 0 {
+		context = append(context, fmt.Sprintf("row %d", err.Row))
+	}
+	if err.Selector != "" {
+		context = append(context, fmt.Sprintf("selector `%s`", err.Selector))
+	}
+	if len(context) != 0 {
+		builder.WriteString(" (")
+		builder.WriteString(strings.Join(context, ", "))
+		builder.WriteString(")")
+	}
+	return builder.String()
+}
+
+func (err *Error) Is(target error) bool {
+	code, ok := target.(SQLError)
+	return ok && err.Code != "" && (err.Code == code || err.Code.Is(code))
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// Annotate attaches the clause and expression an error occurred in. When an
+// error is annotated more than once, the innermost context is kept. The
+// annotations are made to a copy, so the error passed in, which may be
+// shared, is never modified. The same holds for AnnotateRow and
+// AnnotateSelector.
+func Annotate(err error, clause string, expr sqlparser.SQLNode) error {
+	if err == nil {
+		return nil
+	}
+	rs := CopyError(err)
+	if rs.Clause == "" {
+		rs.Clause = clause
+		if expr != nil {
+			rs.Expression = sqlparser.String(expr)
+		}
+	}
+	return rs
+}
+
+func AnnotateRow(err error, row int) error {
+	if err == nil {
+		return nil
+	}
+	rs := CopyError(err)
+	if rs.Row < 0 {
+		rs.Row = row
+	}
+	return rs
+}
+
+func AnnotateSelector(err error, selector string) error {
+	if err == nil {
+		return nil
+	}
+	rs := CopyError(err)
+	if rs.Selector == "" {
+		rs.Selector = selector
+	}
+	return rs
+}
+
+// AsError returns err as an *Error, wrapping it when it is a bare sentinel
+// or an error that did not come from the engine
+func AsError(err error) *Error {
+	if rs, ok := err.(*Error); ok {
+		return rs
+	}
+	if code, ok := err.(SQLError); ok {
+		return &Error{Code: code, Row: -1}
+	}
+	return &Error{Err: err, Row: -1}
+}
+
+// CopyError returns a copy of err as an *Error that can be annotated
+func CopyError(err error) *Error {
+	rs := *AsError(err)
+	return &rs
+}
+
 const (
 	INVALID_CAST       SQLError = SQLError("invalid cast")
 	UNDEFINED_OPERATOR SQLError = SQLError("undefined operator")
@@ -33,4 +178,12 @@ const (
 	UNSUPPORTED_CASE   SQLError = SQLError("unsupported operation")
 	KEY_NOT_FOUND      SQLError = SQLError("key not found")
 	EXPECTATION_FAILED SQLError = SQLError("expectation failed")
+
+	INPUT_LIMIT_EXCEEDED     SQLError = SQLError("input row limit exceeded")
+	RESULT_LIMIT_EXCEEDED    SQLError = SQLError("result row limit exceeded")
+	JOIN_LIMIT_EXCEEDED      SQLError = SQLError("join row limit exceeded")
+	DEPTH_LIMIT_EXCEEDED     SQLError = SQLError("nesting depth limit exceeded")
+	RECURSION_LIMIT_EXCEEDED SQLError = SQLError("recursion limit exceeded")
+	GOROUTINE_LIMIT_EXCEEDED SQLError = SQLError("goroutine limit exceeded")
+	TIME_LIMIT_EXCEEDED      SQLError = SQLError("time limit exceeded")
 )
@@ -0,0 +1,138 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+func TestError(t *testing.T) {
+	cause := errors.New("boom")
+	expr, err := sqlparser.ParseExpr("a > 1")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	test := []struct {
+		Name     string
+		Error    error
+		Expected string
+		Is       error
+	}{
+		{Name: "Extend", Error: INVALID_TYPE.Extend("message"), Expected: "invalid type. message", Is: INVALID_TYPE},
+		{Name: "Describe", Error: INVALID_TYPE.Describe("message"), Expected: "invalid type. message", Is: INVALID_TYPE},
+		{Name: "Annotated Extend", Error: Annotate(INVALID_TYPE.Extend("message"), "WHERE", nil), Expected: "invalid type. message (clause WHERE)", Is: INVALID_TYPE},
+		{Name: "Sentinel", Error: Annotate(KEY_NOT_FOUND, "WHERE", expr), Expected: "key not found (clause WHERE, expression `a > 1`)", Is: KEY_NOT_FOUND},
+		{Name: "Row", Error: AnnotateRow(Annotate(INVALID_CAST.Describe("message"), "SELECT", expr), 2), Expected: "invalid cast. message (clause SELECT, expression `a > 1`, row 2)", Is: INVALID_CAST},
+		{Name: "Innermost", Error: AnnotateRow(AnnotateRow(Annotate(Annotate(INVALID_CAST, "WHERE", nil), "SELECT", nil), 1), 2), Expected: "invalid cast (clause WHERE, row 1)", Is: INVALID_CAST},
+		{Name: "Selector", Error: AnnotateSelector(UNSUPPORTED_CASE, "a.b"), Expected: "unsupported operation (selector `a.b`)", Is: UNSUPPORTED_CASE},
+		{Name: "Cause", Error: AnnotateRow(cause, 0), Expected: "boom (row 0)", Is: cause},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.Error.Error() != test.Expected {
+				t.Fatalf("expected %s but found %s", test.Expected, test.Error.Error())
+			}
+			if !errors.Is(test.Error, test.Is) {
+				t.Fatalf("expected %v to be %v", test.Error, test.Is)
+			}
+			if errors.Is(test.Error, EXPECTATION_FAILED) {
+				t.Fatalf("expected %v not to be %v", test.Error, EXPECTATION_FAILED)
+			}
+		})
+	}
+}
+
+func TestError_Extend(t *testing.T) {
+	var err SQLError = INVALID_TYPE.Extend("message")
+	if !errors.Is(err, INVALID_TYPE) {
+		t.Fatalf("expected %v to be %v", err, INVALID_TYPE)
+	}
+	if errors.Is(INVALID_TYPE, err) || errors.Is(SQLError("invalid typed. message"), INVALID_TYPE) {
+		t.Fatalf("expected only errors extended from %v to be %v", INVALID_TYPE, INVALID_TYPE)
+	}
+}
+
+func TestError_AnnotateCopies(t *testing.T) {
+	shared := KEY_NOT_FOUND.Describe("message")
+	first := AnnotateSelector(AnnotateRow(Annotate(shared, "WHERE", nil), 1), "a")
+	second := Annotate(shared, "SELECT", nil)
+	if shared.Clause != "" || shared.Row != -1 || shared.Selector != "" {
+		t.Fatalf("expected the annotated error not to be modified but found %v", shared)
+	}
+	if first.Error() != "key not found. message (clause WHERE, row 1, selector `a`)" {
+		t.Fatalf("unexpected error %v", first)
+	}
+	if second.Error() != "key not found. message (clause SELECT)" {
+		t.Fatalf("unexpected error %v", second)
+	}
+}
+
+func TestQuery_ErrorContext(t *testing.T) {
+	cause := errors.New("boom")
+	RegisterFunction("test_fail_on_two", func(q *Query, m Map, fo *FunctionOptions, a []any) (any, error) {
+		if a[0] == 2.0 {
+			return nil, cause
+		}
+		return a[0], nil
+	})
+	data := Map{
+		"users": []any{
+			Map{"id": 1.0, "active": true, "name": "John"},
+			Map{"id": 2.0, "name": Map{"first": "Jane"}},
+		},
+		"roles": []any{
+			Map{"id": 1.0, "role": "admin"},
+			Map{"id": 2.0, "role": "user"},
+		},
+	}
+	test := []struct {
+		Name     string
+		Query    string
+		Code     error
+		Clause   string
+		Expr     string
+		Row      int
+		Selector string
+	}{
+		{Name: "Where", Query: "SELECT id FROM `root.users` WHERE active AND id > 0", Code: EXPECTATION_FAILED, Clause: "WHERE", Expr: "active and id > 0", Row: 1},
+		{Name: "Select", Query: "SELECT test_fail_on_two(id) AS x FROM `root.users`", Code: cause, Clause: "SELECT", Expr: "test_fail_on_two(id) as x", Row: 1},
+		{Name: "Selector", Query: "SELECT `name.first` AS first FROM `root.users`", Code: EXPECTATION_FAILED, Clause: "SELECT", Expr: "`name.first` as `first`", Row: 0, Selector: "name.first"},
+		{Name: "Join", Query: "SELECT * FROM `root.users` AS u JOIN `root.roles` AS r ON u.active AND u.id = r.id", Code: EXPECTATION_FAILED, Clause: "JOIN", Expr: "u.active and u.id = r.id", Row: 1},
+	}
+	for _, test := range test {
+		t.Run(test.Name, func(t *testing.T) {
+			query, err := New(data, test.Query, Wrapped(), StrictNulls())
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			_, err = query.Exec()
+			if !errors.Is(err, test.Code) {
+				t.Fatalf("expected %v but found %v", test.Code, err)
+			}
+			var rs *Error
+			if !errors.As(err, &rs) {
+				t.Fatalf("expected a structured error but found %T", err)
+			}
+			found := fmt.Sprintf("%s|%s|%d|%s", rs.Clause, rs.Expression, rs.Row, rs.Selector)
+			expected := fmt.Sprintf("%s|%s|%d|%s", test.Clause, test.Expr, test.Row, test.Selector)
+			if found != expected {
+				t.Fatalf("expected %s but found %s", expected, found)
+			}
+		})
+	}
+}
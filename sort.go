@@ -19,7 +19,10 @@ import (
 	"github.com/vedadiyan/genql/compare"
 )
 
-func Sort(slice []any, orderBy OrderByDefinition) (err error) {
+// SortValues sorts the slice by the ORDER BY values read for every item,
+// given in the same order as the slice. Items that compare equal keep
+// their order and nils are sorted last in both directions.
+func SortValues(slice []any, values [][]any, orderBy OrderByDefinition) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = r.(error)
@@ -28,42 +31,39 @@ func Sort(slice []any, orderBy OrderByDefinition) (err error) {
 	if len(orderBy) == 0 {
 		return nil
 	}
-	sort.Slice(slice, func(i, j int) bool {
-		rs, err := Compare(slice, i, j, orderBy)
-		if err != nil {
-			panic(err)
-		}
-		return rs
+	indexes := make([]int, len(slice))
+	for index := range indexes {
+		indexes[index] = index
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return CompareValues(values[indexes[i]], values[indexes[j]], orderBy)
 	})
+	sorted := make([]any, len(slice))
+	for index, position := range indexes {
+		sorted[index] = slice[position]
+	}
+	copy(slice, sorted)
 	return nil
 }
 
-func Compare(slice []any, i int, j int, orderBy OrderByDefinition) (bool, error) {
-	if len(orderBy) == 0 {
-		return false, nil
-	}
-	key := orderBy[0].Key
-	direction := 1
-	if orderBy[0].Value {
-		direction = -1
-	}
-	first, err := ExecReader(slice[i], key)
-	if err != nil {
-		return false, err
-	}
-	if first == nil {
-		return false, nil
-	}
-	second, err := ExecReader(slice[j], key)
-	if err != nil {
-		return false, err
-	}
-	if second == nil {
-		return true, nil
-	}
-	res := compare.Compare(first, second)
-	if res == 0 {
-		return Compare(slice, i, j, orderBy[1:])
+// CompareValues reports whether the first ORDER BY values sort before the
+// second
+func CompareValues(first []any, second []any, orderBy OrderByDefinition) bool {
+	for index, orderBy := range orderBy {
+		direction := 1
+		if orderBy.Value {
+			direction = -1
+		}
+		if first[index] == nil || second[index] == nil {
+			if first[index] == nil && second[index] == nil {
+				continue
+			}
+			return second[index] == nil
+		}
+		res := compare.Values(first[index], second[index])
+		if res != 0 {
+			return res == direction
+		}
 	}
-	return res == direction, nil
+	return false
 }
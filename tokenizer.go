@@ -0,0 +1,258 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type (
+	// TokenKind tells what a token of a query is
+	TokenKind int
+	// Token is a piece of a query found by Tokenize. Start and End are the
+	// offsets of the token in the query.
+	Token struct {
+		Kind  TokenKind
+		Start int
+		End   int
+		Text  string
+	}
+	// Tokens are the tokens of a query in order
+	Tokens []Token
+	// Edits are the insertions and replacements the rewrites make to a
+	// query, by offset in the original query
+	Edits struct {
+		inserts  map[int]string
+		replaces map[int]replacement
+	}
+	replacement struct {
+		end  int
+		text string
+	}
+	// Rewrite collects the edits for one of the forms the parser does not
+	// understand
+	Rewrite func(str string, tokens Tokens, edits *Edits) error
+)
+
+const (
+	WordToken TokenKind = iota
+	QuotedToken
+	CommentToken
+	SpaceToken
+	SymbolToken
+)
+
+// Tokenize splits a query into words, quoted text, comments, spaces and
+// single character symbols. Comments are written as /* */, as # up to the
+// end of the line, or as -- followed by a space up to the end of the line.
+func Tokenize(str string) (Tokens, error) {
+	tokens := make(Tokens, 0)
+	for i := 0; i < len(str); {
+		r := str[i]
+		kind := SymbolToken
+		end := i + 1
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			{
+				quotedEnd, err := QuotedEnd(str, i)
+				if err != nil {
+					return nil, err
+				}
+				kind, end = QuotedToken, quotedEnd
+			}
+		case strings.HasPrefix(str[i:], "/*"):
+			{
+				index := strings.Index(str[i+2:], "*/")
+				if index == -1 {
+					return nil, fmt.Errorf("unterminated comment")
+				}
+				kind, end = CommentToken, i+2+index+2
+			}
+		case r == '#' || (strings.HasPrefix(str[i:], "--") && (i+2 == len(str) || unicode.IsSpace(rune(str[i+2])))):
+			{
+				kind, end = CommentToken, len(str)
+				if index := strings.IndexByte(str[i:], '\n'); index != -1 {
+					end = i + index
+				}
+			}
+		case IsWordPart(r):
+			{
+				kind = WordToken
+				for end < len(str) && IsWordPart(str[end]) {
+					end++
+				}
+			}
+		case unicode.IsSpace(rune(r)):
+			{
+				kind = SpaceToken
+				for end < len(str) && unicode.IsSpace(rune(str[end])) {
+					end++
+				}
+			}
+		}
+		tokens = append(tokens, Token{Kind: kind, Start: i, End: end, Text: str[i:end]})
+		i = end
+	}
+	return tokens, nil
+}
+
+// Next returns the index of the first token after i that is neither a
+// space nor a comment, or the number of tokens when there is none
+func (tokens Tokens) Next(i int) int {
+	for i++; i < len(tokens); i++ {
+		if tokens[i].Kind != SpaceToken && tokens[i].Kind != CommentToken {
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+// Previous returns the index of the last token before i that is neither a
+// space nor a comment, or -1 when there is none
+func (tokens Tokens) Previous(i int) int {
+	for i--; i >= 0; i-- {
+		if tokens[i].Kind != SpaceToken && tokens[i].Kind != CommentToken {
+			return i
+		}
+	}
+	return -1
+}
+
+// Is reports whether the token at i is one of the words, ignoring case
+func (tokens Tokens) Is(i int, words ...string) bool {
+	if i < 0 || i >= len(tokens) || tokens[i].Kind != WordToken {
+		return false
+	}
+	for _, word := range words {
+		if strings.EqualFold(tokens[i].Text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSymbol reports whether the token at i is one of the symbols
+func (tokens Tokens) IsSymbol(i int, symbols string) bool {
+	return i >= 0 && i < len(tokens) && tokens[i].Kind == SymbolToken && strings.Contains(symbols, tokens[i].Text)
+}
+
+// IsName reports whether the token at i starts a name, which is a word
+// that is not a number or a name in backticks or double quotes
+func (tokens Tokens) IsName(i int) bool {
+	if i < 0 || i >= len(tokens) {
+		return false
+	}
+	switch tokens[i].Kind {
+	case WordToken:
+		{
+			return IsWordStart(tokens[i].Text[0])
+		}
+	case QuotedToken:
+		{
+			return tokens[i].Text[0] != '\''
+		}
+	}
+	return false
+}
+
+// NameEnd returns the index right after the last token of the name
+// starting at i, which can be made of several names joined by dots
+func (tokens Tokens) NameEnd(i int) int {
+	for i < len(tokens) && (tokens.IsName(i) || tokens.IsSymbol(i, ".")) {
+		i++
+	}
+	return i
+}
+
+// Close returns the index of the parenthesis closing the one at i
+func (tokens Tokens) Close(i int) (int, error) {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch {
+		case tokens.IsSymbol(i, "("):
+			{
+				depth++
+			}
+		case tokens.IsSymbol(i, ")"):
+			{
+				depth--
+				if depth == 0 {
+					return i, nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated (")
+}
+
+func NewEdits() *Edits {
+	return &Edits{
+		inserts:  make(map[int]string),
+		replaces: make(map[int]replacement),
+	}
+}
+
+// Insert adds text at an offset, after the text already inserted there
+func (edits *Edits) Insert(offset int, text string) {
+	edits.inserts[offset] += text
+}
+
+// Replace replaces the text between start and end
+func (edits *Edits) Replace(start int, end int, text string) {
+	if end <= start {
+		edits.Insert(start, text)
+		return
+	}
+	edits.replaces[start] = replacement{end: end, text: text}
+}
+
+// Apply returns the query with the edits made to it
+func (edits *Edits) Apply(str string) string {
+	if len(edits.inserts) == 0 && len(edits.replaces) == 0 {
+		return str
+	}
+	buffer := bytes.NewBufferString("")
+	for i := 0; i <= len(str); i++ {
+		buffer.WriteString(edits.inserts[i])
+		if replacement, ok := edits.replaces[i]; ok {
+			buffer.WriteString(replacement.text)
+			i = replacement.end - 1
+			continue
+		}
+		if i < len(str) {
+			buffer.WriteByte(str[i])
+		}
+	}
+	return buffer.String()
+}
+
+// RewriteQuery tokenizes a query once and makes the edits of every rewrite
+// to it. The edits are collected on the original query, so a rewrite never
+// sees what another rewrite has changed.
+func RewriteQuery(str string, rewrites ...Rewrite) (string, error) {
+	tokens, err := Tokenize(str)
+	if err != nil {
+		return "", err
+	}
+	edits := NewEdits()
+	for _, rewrite := range rewrites {
+		if err := rewrite(str, tokens, edits); err != nil {
+			return "", err
+		}
+	}
+	return edits.Apply(str), nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      string
+		expectErr bool
+	}{
+		{
+			name:  "Words And Symbols",
+			input: "a.b>=1",
+			want:  "[{0 a} {4 .} {0 b} {4 >} {4 =} {0 1}]",
+		},
+		{
+			name:  "Quoted",
+			input: "'it''s' `x` \"y\"",
+			want:  "[{1 'it'} {1 's'} {3  } {1 `x`} {3  } {1 \"y\"}]",
+		},
+		{
+			name:  "Comments",
+			input: "a /* b */ c -- d\n# e\nf",
+			want:  "[{0 a} {3  } {2 /* b */} {3  } {0 c} {3  } {2 -- d} {3 \n} {2 # e} {3 \n} {0 f}]",
+		},
+		{
+			name:  "Minus",
+			input: "a--1",
+			want:  "[{0 a} {4 -} {4 -} {0 1}]",
+		},
+		{
+			name:  "Comment At End",
+			input: "a --",
+			want:  "[{0 a} {3  } {2 --}]",
+		},
+		{
+			name:      "Unterminated Comment",
+			input:     "a /* b",
+			expectErr: true,
+		},
+		{
+			name:      "Unterminated Quote",
+			input:     "a 'b",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			result := make([]string, 0)
+			for _, token := range tokens {
+				if tt.input[token.Start:token.End] != token.Text {
+					t.Fatalf("token %v does not match its offsets", token)
+				}
+				result = append(result, fmt.Sprintf("{%d %s}", token.Kind, token.Text))
+			}
+			if out := fmt.Sprintf("%v", result); out != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestEdits(t *testing.T) {
+	edits := NewEdits()
+	edits.Insert(0, "(")
+	edits.Replace(2, 5, "x")
+	edits.Insert(7, ")")
+	edits.Insert(7, "!")
+	edits.Replace(6, 6, "-")
+	if out := edits.Apply("a bcd ef"); out != "(a x -e)!f" {
+		t.Errorf("expected %v, got %v", "(a x -e)!f", out)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"strings"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+// SetOperator returns the set operation a UNION node stands for.
+// RewriteSetOperations records INTERSECT and EXCEPT as a comment on the
+// first SELECT of the right operand.
+func SetOperator(expr *sqlparser.Union) string {
+	comments := sqlparser.String(sqlparser.GetFirstSelect(expr.Right).Comments)
+	switch {
+	case strings.Contains(comments, "genql:intersect"):
+		{
+			return "INTERSECT"
+		}
+	case strings.Contains(comments, "genql:except"):
+		{
+			return "EXCEPT"
+		}
+	}
+	return "UNION"
+}
+
+// SetOperation combines the rows of both operands of a set operation. Rows
+// are compared by their structural keys and keep the order of the left
+// operand, followed by the right one for a UNION. Without ALL duplicates
+// are removed. With ALL, INTERSECT keeps a row as many times as it occurs
+// in both operands and EXCEPT as many more times as it occurs in the left
+// one.
+func SetOperation(operator string, distinct bool, left []any, right []any) []any {
+	slice := make([]any, 0)
+	switch operator {
+	case "UNION":
+		{
+			slice = append(slice, left...)
+			slice = append(slice, right...)
+			if !distinct {
+				return slice
+			}
+			return DistinctRows(slice)
+		}
+	}
+	counts := make(map[string]int)
+	for _, row := range right {
+		counts[StructuralKey(row)]++
+	}
+	seen := make(map[string]bool)
+	for _, row := range left {
+		key := StructuralKey(row)
+		if distinct {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		switch operator {
+		case "INTERSECT":
+			{
+				if counts[key] == 0 {
+					continue
+				}
+				if !distinct {
+					counts[key]--
+				}
+			}
+		case "EXCEPT":
+			{
+				if counts[key] != 0 {
+					if !distinct {
+						counts[key]--
+					}
+					continue
+				}
+			}
+		}
+		slice = append(slice, row)
+	}
+	return slice
+}
+
+// DistinctRows returns the first occurrence of every row
+func DistinctRows(rows []any) []any {
+	slice := make([]any, 0, len(rows))
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		key := StructuralKey(row)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		slice = append(slice, row)
+	}
+	return slice
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSetOperations(t *testing.T) {
+	data := Map{
+		"a": []any{
+			Map{"id": 1.0, "name": "x"},
+			Map{"id": 2.0, "name": "y"},
+			Map{"id": 2.0, "name": "y"},
+			Map{"id": 3.0, "name": "z"},
+		},
+		"b": []any{
+			Map{"id": 2.0, "name": "y"},
+			Map{"id": 3.0, "name": "z"},
+			Map{"id": 4.0, "name": "w"},
+		},
+		"c": []any{
+			Map{"ref": 3.0},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Union",
+			query: "SELECT id FROM `root.a` UNION SELECT id FROM `root.b`",
+			want:  "[map[id:1] map[id:2] map[id:3] map[id:4]]",
+		},
+		{
+			name:  "Union All",
+			query: "SELECT id FROM `root.a` UNION ALL SELECT id FROM `root.b`",
+			want:  "[map[id:1] map[id:2] map[id:2] map[id:3] map[id:2] map[id:3] map[id:4]]",
+		},
+		{
+			name:  "Keywords In Comments",
+			query: "SELECT id FROM `root.a` /* x EXCEPT y */ WHERE id = 1 -- INTERSECT SELECT\n# UNION",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Except With Comments",
+			query: "SELECT id FROM `root.a` EXCEPT /* SELECT 1 */ SELECT id FROM `root.b` -- EXCEPT",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Chained Union",
+			query: "SELECT id FROM `root.c` UNION ALL SELECT id FROM `root.b` UNION SELECT id FROM `root.a`",
+			want:  "[map[id:<nil>] map[id:2] map[id:3] map[id:4] map[id:1]]",
+		},
+		{
+			name:  "Intersect",
+			query: "SELECT id FROM `root.a` INTERSECT SELECT id FROM `root.b`",
+			want:  "[map[id:2] map[id:3]]",
+		},
+		{
+			name:  "Intersect All",
+			query: "SELECT id FROM `root.a` INTERSECT ALL SELECT id FROM `root.a` WHERE id > 1",
+			want:  "[map[id:2] map[id:2] map[id:3]]",
+		},
+		{
+			name:  "Except",
+			query: "SELECT id FROM `root.a` EXCEPT SELECT id FROM `root.b`",
+			want:  "[map[id:1]]",
+		},
+		{
+			name:  "Except All",
+			query: "SELECT id FROM `root.a` EXCEPT ALL SELECT id FROM `root.b`",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Intersect Precedence",
+			query: "SELECT id FROM `root.a` EXCEPT SELECT id FROM `root.b` INTERSECT SELECT ref AS id FROM `root.c`",
+			want:  "[map[id:1] map[id:2]]",
+		},
+		{
+			name:  "Parenthesised Operand",
+			query: "SELECT id FROM `root.a` EXCEPT (SELECT id FROM `root.b` EXCEPT SELECT ref FROM `root.c`)",
+			want:  "[map[id:1] map[id:3]]",
+		},
+		{
+			name:  "Column Names From First Operand",
+			query: "SELECT id AS n FROM `root.c` UNION SELECT ref FROM `root.c`",
+			want:  "[map[n:<nil>] map[n:3]]",
+		},
+		{
+			name:  "Order By And Limit",
+			query: "SELECT id, name AS label FROM `root.a` UNION SELECT id, name FROM `root.b` ORDER BY label DESC LIMIT 3",
+			want:  "[map[id:3 label:z] map[id:2 label:y] map[id:1 label:x]]",
+		},
+		{
+			name:  "Order By Position",
+			query: "SELECT name FROM `root.a` INTERSECT SELECT name FROM `root.b` ORDER BY 1",
+			want:  "[map[name:y] map[name:z]]",
+		},
+		{
+			name:  "Subquery",
+			query: "SELECT COUNT(*) AS c FROM (SELECT id FROM `root.a` EXCEPT SELECT id FROM `root.b`) t",
+			want:  "[map[c:1]]",
+		},
+		{
+			name:    "Column Count",
+			query:   "SELECT id, name FROM `root.a` UNION SELECT id FROM `root.b`",
+			wantErr: EXPECTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, Wrapped())
+			if err == nil {
+				var result any
+				result, err = q.Exec()
+				if err == nil && tt.wantErr == nil {
+					if fmt.Sprintf("%v", result) != tt.want {
+						t.Errorf("Exec() = %v, want %v", result, tt.want)
+					}
+					return
+				}
+			}
+			if tt.wantErr == nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
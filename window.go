@@ -0,0 +1,949 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/vedadiyan/sqlparser/pkg/sqlparser"
+)
+
+// windowKey holds the values of the window functions of a query in the
+// rows they are computed for. It is never part of a selected row.
+const windowKey = "\x00window"
+
+type (
+	// Window is a window function call with its resolved window
+	Window struct {
+		Expr      sqlparser.Expr
+		Partition sqlparser.Exprs
+		Order     sqlparser.OrderBy
+		Frame     *sqlparser.FrameClause
+	}
+	// windowFrame is the range of rows, in partition order, a window
+	// function is computed over. The frame is empty when start > end.
+	windowFrame struct {
+		start int
+		end   int
+	}
+	// runningAggregate is the state of a built-in aggregate computed over
+	// a growing frame, to which rows are added one at a time
+	runningAggregate struct {
+		name    string
+		rows    int
+		numbers int
+		sum     float64
+		min     float64
+		max     float64
+	}
+)
+
+// runningAggregates are the built-in aggregates that can be computed over
+// a growing frame by adding the rows it gains to the previous value
+var runningAggregates = map[string]Function{
+	"sum":   SumFunc,
+	"avg":   AvgFunc,
+	"min":   MinFunc,
+	"max":   MaxFunc,
+	"count": CountFunc,
+}
+
+// IsWindowFunction reports whether an expression is a window function call
+func IsWindowFunction(expr sqlparser.SQLNode) bool {
+	switch expr.(type) {
+	case *sqlparser.ArgumentLessWindowExpr, *sqlparser.FirstOrLastValueExpr, *sqlparser.NtileExpr, *sqlparser.NTHValueExpr, *sqlparser.LagLeadExpr:
+		{
+			return true
+		}
+	}
+	return false
+}
+
+// WindowAggregate returns the aggregate function a window function call
+// stands for. Aggregates followed by an OVER clause are carried by the
+// parser as NTH_VALUE(aggregate, 0), see RewriteWindowAggregates.
+func WindowAggregate(expr sqlparser.Expr) (sqlparser.AggrFunc, bool) {
+	nthValue, ok := expr.(*sqlparser.NTHValueExpr)
+	if !ok {
+		return nil, false
+	}
+	literal, ok := nthValue.N.(*sqlparser.Literal)
+	if !ok || literal.Type != sqlparser.IntVal || literal.Val != "0" {
+		return nil, false
+	}
+	aggrFunc, ok := nthValue.Expr.(sqlparser.AggrFunc)
+	return aggrFunc, ok
+}
+
+// FindWindows returns the window function calls of an expression without
+// looking into subqueries, which are built on their own
+func FindWindows(expr sqlparser.SQLNode) []sqlparser.Expr {
+	windows := make([]sqlparser.Expr, 0)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if _, ok := node.(*sqlparser.Subquery); ok {
+			return false, nil
+		}
+		if IsWindowFunction(node) {
+			windows = append(windows, node.(sqlparser.Expr))
+			return false, nil
+		}
+		return true, nil
+	}, expr)
+	return windows
+}
+
+// BuildWindows compiles the window function calls of the SELECT and ORDER
+// BY clauses. Window functions are computed after GROUP BY and HAVING, so
+// they are rejected anywhere else.
+func BuildWindows(query *Query, slct *sqlparser.Select) error {
+	if slct.Where != nil && len(FindWindows(slct.Where.Expr)) != 0 {
+		return Annotate(EXPECTATION_FAILED.Describe("window functions are not allowed in WHERE"), "WHERE", slct.Where.Expr)
+	}
+	if slct.Having != nil && len(FindWindows(slct.Having.Expr)) != 0 {
+		return Annotate(EXPECTATION_FAILED.Describe("window functions are not allowed in HAVING"), "HAVING", slct.Having.Expr)
+	}
+	for _, expr := range query.groupExpressions {
+		if len(FindWindows(expr)) != 0 {
+			return Annotate(EXPECTATION_FAILED.Describe("window functions are not allowed in GROUP BY"), "GROUP BY", expr)
+		}
+	}
+	named := make(map[string]*sqlparser.WindowSpecification)
+	for _, namedWindow := range slct.Windows {
+		for _, definition := range namedWindow.Windows {
+			named[definition.Name.Lowered()] = definition.WindowSpec
+		}
+	}
+	windows := make([]sqlparser.Expr, 0)
+	for _, expr := range query.selectDefinition {
+		windows = append(windows, FindWindows(expr)...)
+	}
+	for _, expr := range query.orderByExpressions {
+		if expr != nil {
+			windows = append(windows, FindWindows(expr)...)
+		}
+	}
+	for _, expr := range windows {
+		window, err := BuildWindow(expr, named)
+		if err != nil {
+			return Annotate(err, "SELECT", expr)
+		}
+		if query.windowLookup == nil {
+			query.windowLookup = make(map[sqlparser.Expr]int)
+		}
+		query.windowLookup[expr] = len(query.windowDefinitions)
+		query.windowDefinitions = append(query.windowDefinitions, window)
+	}
+	return nil
+}
+
+func BuildWindow(expr sqlparser.Expr, named map[string]*sqlparser.WindowSpecification) (*Window, error) {
+	var over *sqlparser.OverClause
+	var args sqlparser.Exprs
+	switch expr := expr.(type) {
+	case *sqlparser.ArgumentLessWindowExpr:
+		{
+			over = expr.OverClause
+		}
+	case *sqlparser.NtileExpr:
+		{
+			over = expr.OverClause
+			args = sqlparser.Exprs{expr.N}
+		}
+	case *sqlparser.FirstOrLastValueExpr:
+		{
+			if expr.NullTreatmentClause != nil && expr.NullTreatmentClause.Type == sqlparser.IgnoreNullsType {
+				return nil, UNSUPPORTED_CASE.Describe("IGNORE NULLS is not supported")
+			}
+			over = expr.OverClause
+			args = sqlparser.Exprs{expr.Expr}
+		}
+	case *sqlparser.LagLeadExpr:
+		{
+			if expr.NullTreatmentClause != nil && expr.NullTreatmentClause.Type == sqlparser.IgnoreNullsType {
+				return nil, UNSUPPORTED_CASE.Describe("IGNORE NULLS is not supported")
+			}
+			over = expr.OverClause
+			args = sqlparser.Exprs{expr.Expr, expr.N, expr.Default}
+		}
+	case *sqlparser.NTHValueExpr:
+		{
+			if expr.NullTreatmentClause != nil && expr.NullTreatmentClause.Type == sqlparser.IgnoreNullsType {
+				return nil, UNSUPPORTED_CASE.Describe("IGNORE NULLS is not supported")
+			}
+			over = expr.OverClause
+			if aggrFunc, ok := WindowAggregate(expr); ok {
+				for _, arg := range aggrFunc.GetArgs() {
+					args = append(args, arg)
+				}
+				break
+			}
+			if _, ok := expr.Expr.(*sqlparser.FuncExpr); ok {
+				if literal, ok := expr.N.(*sqlparser.Literal); ok && literal.Val == "0" {
+					return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("%s is not an aggregate function", sqlparser.String(expr.Expr)))
+				}
+			}
+			args = sqlparser.Exprs{expr.Expr, expr.N}
+		}
+	default:
+		{
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%T is not a window function", expr))
+		}
+	}
+	for _, arg := range args {
+		if arg != nil && len(FindWindows(arg)) != 0 {
+			return nil, EXPECTATION_FAILED.Describe("window functions cannot be nested")
+		}
+	}
+	spec, err := WindowSpecification(over, named)
+	if err != nil {
+		return nil, err
+	}
+	window := &Window{
+		Expr:      expr,
+		Partition: spec.PartitionClause,
+		Order:     spec.OrderClause,
+		Frame:     spec.FrameClause,
+	}
+	if window.Frame != nil {
+		err := BuildFrame(window)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return window, nil
+}
+
+// WindowSpecification resolves the window of an OVER clause. A window may
+// refer to a named window of the WINDOW clause and add an ORDER BY or a
+// frame to it.
+func WindowSpecification(over *sqlparser.OverClause, named map[string]*sqlparser.WindowSpecification) (*sqlparser.WindowSpecification, error) {
+	if over == nil {
+		return nil, EXPECTATION_FAILED.Describe("window functions require an OVER clause")
+	}
+	if !over.WindowName.IsEmpty() {
+		spec, ok := named[over.WindowName.Lowered()]
+		if !ok {
+			return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("window %s is not defined", over.WindowName.String()))
+		}
+		return WindowSpecification(&sqlparser.OverClause{WindowSpec: spec}, named)
+	}
+	spec := over.WindowSpec
+	if spec == nil {
+		return &sqlparser.WindowSpecification{}, nil
+	}
+	if spec.Name.IsEmpty() {
+		return spec, nil
+	}
+	base, ok := named[spec.Name.Lowered()]
+	if !ok {
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("window %s is not defined", spec.Name.String()))
+	}
+	if len(spec.PartitionClause) != 0 {
+		return nil, EXPECTATION_FAILED.Describe(fmt.Sprintf("cannot override PARTITION BY of window %s", spec.Name.String()))
+	}
+	rs := *base
+	rs.Name = sqlparser.IdentifierCI{}
+	if len(spec.OrderClause) != 0 {
+		rs.OrderClause = spec.OrderClause
+	}
+	if spec.FrameClause != nil {
+		rs.FrameClause = spec.FrameClause
+	}
+	return &rs, nil
+}
+
+func BuildFrame(window *Window) error {
+	frame := window.Frame
+	if frame.Start == nil {
+		return EXPECTATION_FAILED.Describe("window frames require a start")
+	}
+	if frame.Start.Type == sqlparser.UnboundedFollowingType {
+		return EXPECTATION_FAILED.Describe("window frames cannot start at UNBOUNDED FOLLOWING")
+	}
+	if frame.End != nil && frame.End.Type == sqlparser.UnboundedPrecedingType {
+		return EXPECTATION_FAILED.Describe("window frames cannot end at UNBOUNDED PRECEDING")
+	}
+	if frame.Unit != sqlparser.FrameRangeType {
+		return nil
+	}
+	for _, point := range []*sqlparser.FramePoint{frame.Start, frame.End} {
+		if point == nil || (point.Type != sqlparser.ExprPrecedingType && point.Type != sqlparser.ExprFollowingType) {
+			continue
+		}
+		if len(window.Order) != 1 {
+			return EXPECTATION_FAILED.Describe("RANGE frames with an offset require exactly one ORDER BY expression")
+		}
+	}
+	return nil
+}
+
+// ExecWindows computes the window functions of the query for every row.
+// The rows are copied, so the data being queried is never modified, and
+// the values are kept under windowKey for the SELECT and ORDER BY clauses.
+func ExecWindows(query *Query, current []any) ([]any, error) {
+	if len(query.windowDefinitions) == 0 {
+		return current, nil
+	}
+	rows := make([]Map, 0, len(current))
+	positions := make([]int, 0, len(current))
+	slice := make([]any, len(current))
+	copy(slice, current)
+	for index, item := range current {
+		row, ok := item.(Map)
+		if !ok {
+			continue
+		}
+		rows = append(rows, row)
+		positions = append(positions, index)
+	}
+	values := make([][]any, len(rows))
+	for index := range values {
+		values[index] = make([]any, len(query.windowDefinitions))
+	}
+	for windowIndex, window := range query.windowDefinitions {
+		err := ExecWindow(query, window, rows, func(row int, value any) {
+			values[row][windowIndex] = value
+		})
+		if err != nil {
+			return nil, Annotate(err, "SELECT", window.Expr)
+		}
+	}
+	for index, row := range rows {
+		windowed := make(Map, len(row)+1)
+		for key, value := range row {
+			windowed[key] = value
+		}
+		windowed[windowKey] = values[index]
+		slice[positions[index]] = windowed
+	}
+	return slice, nil
+}
+
+// ExecWindow computes a window function for every row, partition by
+// partition, and reports the value of each row through set
+func ExecWindow(query *Query, window *Window, rows []Map, set func(row int, value any)) error {
+	partitions, err := WindowPartitions(query, window, rows)
+	if err != nil {
+		return err
+	}
+	orderBy := make(OrderByDefinition, 0, len(window.Order))
+	for _, order := range window.Order {
+		orderBy = append(orderBy, struct {
+			Key   string
+			Value bool
+		}{
+			Key:   sqlparser.String(order.Expr),
+			Value: order.Direction == sqlparser.AscOrder,
+		})
+	}
+	for _, partition := range partitions {
+		if err := query.contextErr(); err != nil {
+			return err
+		}
+		keys := make([][]any, len(partition))
+		for index, row := range partition {
+			keys[index] = make([]any, len(window.Order))
+			for position, order := range window.Order {
+				value, err := WindowValue(query, rows[row], order.Expr)
+				if err != nil {
+					return err
+				}
+				keys[index][position] = value
+			}
+		}
+		sorted := make([]int, len(partition))
+		for index := range sorted {
+			sorted[index] = index
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return CompareValues(keys[sorted[i]], keys[sorted[j]], orderBy)
+		})
+		ordered := make([]Map, len(partition))
+		orderedKeys := make([][]any, len(partition))
+		for index, position := range sorted {
+			ordered[index] = rows[partition[position]]
+			orderedKeys[index] = keys[position]
+		}
+		results, err := WindowResults(query, window, ordered, orderedKeys, orderBy)
+		if err != nil {
+			return err
+		}
+		for index, position := range sorted {
+			set(partition[position], results[index])
+		}
+	}
+	return nil
+}
+
+// WindowPartitions splits the rows by the PARTITION BY values of a window
+// in the order the first row of each partition appears in
+func WindowPartitions(query *Query, window *Window, rows []Map) ([][]int, error) {
+	if len(window.Partition) == 0 {
+		partition := make([]int, len(rows))
+		for index := range partition {
+			partition[index] = index
+		}
+		return [][]int{partition}, nil
+	}
+	partitions := make([][]int, 0)
+	lookup := make(map[string]int)
+	for index, row := range rows {
+		values := make([]any, len(window.Partition))
+		for position, expr := range window.Partition {
+			value, err := WindowValue(query, row, expr)
+			if err != nil {
+				return nil, err
+			}
+			values[position] = value
+		}
+		key := StructuralKey(values)
+		partition, ok := lookup[key]
+		if !ok {
+			partition = len(partitions)
+			lookup[key] = partition
+			partitions = append(partitions, make([]int, 0))
+		}
+		partitions[partition] = append(partitions[partition], index)
+	}
+	return partitions, nil
+}
+
+// WindowResults computes a window function for the rows of a partition,
+// given in window order along with their ORDER BY values
+func WindowResults(query *Query, window *Window, rows []Map, keys [][]any, orderBy OrderByDefinition) ([]any, error) {
+	length := len(rows)
+	results := make([]any, length)
+	// Rows are peers when their ORDER BY values are equal. Every row is
+	// a peer of every other row when the window has no ORDER BY.
+	peerStart := make([]int, length)
+	peerEnd := make([]int, length)
+	peerGroup := make([]int, length)
+	for index := 0; index < length; index++ {
+		if index > 0 && !CompareValues(keys[index-1], keys[index], orderBy) {
+			peerStart[index] = peerStart[index-1]
+			peerGroup[index] = peerGroup[index-1]
+			continue
+		}
+		peerStart[index] = index
+		if index > 0 {
+			peerGroup[index] = peerGroup[index-1] + 1
+		}
+	}
+	for index := length - 1; index >= 0; index-- {
+		if index < length-1 && peerStart[index+1] == peerStart[index] {
+			peerEnd[index] = peerEnd[index+1]
+			continue
+		}
+		peerEnd[index] = index
+	}
+	switch expr := window.Expr.(type) {
+	case *sqlparser.ArgumentLessWindowExpr:
+		{
+			for index := range rows {
+				switch expr.Type {
+				case sqlparser.RowNumberExprType:
+					{
+						results[index] = index + 1
+					}
+				case sqlparser.RankExprType:
+					{
+						results[index] = peerStart[index] + 1
+					}
+				case sqlparser.DenseRankExprType:
+					{
+						results[index] = peerGroup[index] + 1
+					}
+				case sqlparser.PercentRankExprType:
+					{
+						if length == 1 {
+							results[index] = float64(0)
+							continue
+						}
+						results[index] = float64(peerStart[index]) / float64(length-1)
+					}
+				case sqlparser.CumeDistExprType:
+					{
+						results[index] = float64(peerEnd[index]+1) / float64(length)
+					}
+				}
+			}
+			return results, nil
+		}
+	case *sqlparser.NtileExpr:
+		{
+			buckets, err := WindowCount(query, expr.N, 1, "NTILE")
+			if err != nil {
+				return nil, err
+			}
+			if buckets == 0 {
+				return nil, EXPECTATION_FAILED.Describe("NTILE expects a positive number of buckets")
+			}
+			// The first length % buckets buckets hold one more row
+			size, remainder := length/buckets, length%buckets
+			for index := range rows {
+				if index < remainder*(size+1) {
+					results[index] = index/(size+1) + 1
+					continue
+				}
+				results[index] = remainder + (index-remainder*(size+1))/size + 1
+			}
+			return results, nil
+		}
+	case *sqlparser.LagLeadExpr:
+		{
+			offset, err := WindowCount(query, expr.N, 1, "LAG and LEAD")
+			if err != nil {
+				return nil, err
+			}
+			if expr.Type == sqlparser.LagExprType {
+				offset = -offset
+			}
+			for index, row := range rows {
+				target := index + offset
+				if target < 0 || target >= length {
+					if expr.Default == nil {
+						continue
+					}
+					value, err := WindowValue(query, row, expr.Default)
+					if err != nil {
+						return nil, err
+					}
+					results[index] = value
+					continue
+				}
+				value, err := WindowValue(query, rows[target], expr.Expr)
+				if err != nil {
+					return nil, err
+				}
+				results[index] = value
+			}
+			return results, nil
+		}
+	}
+	frames, err := WindowFrames(query, window, keys, peerStart, peerEnd)
+	if err != nil {
+		return nil, err
+	}
+	if aggrFunc, ok := WindowAggregate(window.Expr); ok {
+		running, ok, err := RunningAggregate(query, aggrFunc, rows, frames)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return running, nil
+		}
+		for index, frame := range frames {
+			// Peers share their frame unless the frame is made of rows
+			if index > 0 && frame == frames[index-1] {
+				results[index] = results[index-1]
+				continue
+			}
+			slice := make([]any, 0)
+			for position := frame.start; position <= frame.end; position++ {
+				slice = append(slice, rows[position])
+			}
+			value, err := AggregateOver(query, aggrFunc, slice)
+			if err != nil {
+				return nil, err
+			}
+			results[index] = value
+		}
+		return results, nil
+	}
+	var valueExpr sqlparser.Expr
+	// position picks the row of a frame the value is read from
+	var position func(frame windowFrame) int
+	switch expr := window.Expr.(type) {
+	case *sqlparser.FirstOrLastValueExpr:
+		{
+			valueExpr = expr.Expr
+			position = func(frame windowFrame) int {
+				if expr.Type == sqlparser.LastValueExprType {
+					return frame.end
+				}
+				return frame.start
+			}
+		}
+	case *sqlparser.NTHValueExpr:
+		{
+			nth, err := WindowCount(query, expr.N, 1, "NTH_VALUE")
+			if err != nil {
+				return nil, err
+			}
+			if nth == 0 {
+				return nil, EXPECTATION_FAILED.Describe("NTH_VALUE expects a positive position")
+			}
+			valueExpr = expr.Expr
+			position = func(frame windowFrame) int {
+				if expr.FromFirstLastClause != nil && expr.FromFirstLastClause.Type == sqlparser.FromLastType {
+					if frame.end-nth+1 < frame.start {
+						return -1
+					}
+					return frame.end - nth + 1
+				}
+				if frame.start+nth-1 > frame.end {
+					return -1
+				}
+				return frame.start + nth - 1
+			}
+		}
+	default:
+		{
+			return nil, UNSUPPORTED_CASE.Describe(fmt.Sprintf("%T is not a window function", window.Expr))
+		}
+	}
+	for index, frame := range frames {
+		if frame.start > frame.end {
+			continue
+		}
+		target := position(frame)
+		if target < 0 {
+			continue
+		}
+		value, err := WindowValue(query, rows[target], valueExpr)
+		if err != nil {
+			return nil, err
+		}
+		results[index] = value
+	}
+	return results, nil
+}
+
+// WindowFrames returns the frame of every row of a partition. Without a
+// frame clause the frame runs from the start of the partition to the last
+// peer of the row, which is the whole partition when there is no ORDER BY.
+func WindowFrames(query *Query, window *Window, keys [][]any, peerStart []int, peerEnd []int) ([]windowFrame, error) {
+	length := len(keys)
+	frames := make([]windowFrame, length)
+	frame := window.Frame
+	if frame == nil {
+		for index := range frames {
+			frames[index] = windowFrame{start: 0, end: peerEnd[index]}
+		}
+		return frames, nil
+	}
+	end := frame.End
+	if end == nil {
+		end = &sqlparser.FramePoint{Type: sqlparser.CurrentRowType}
+	}
+	if frame.Unit == sqlparser.FrameRowsType {
+		start, err := FrameOffset(query, frame.Start)
+		if err != nil {
+			return nil, err
+		}
+		finish, err := FrameOffset(query, end)
+		if err != nil {
+			return nil, err
+		}
+		for index := range frames {
+			frames[index] = windowFrame{
+				start: FrameRow(frame.Start, start, index, length),
+				end:   FrameRow(end, finish, index, length),
+			}
+			if frames[index].start < 0 {
+				frames[index].start = 0
+			}
+			if frames[index].end > length-1 {
+				frames[index].end = length - 1
+			}
+		}
+		return frames, nil
+	}
+	// RANGE frames with an offset compare the single ORDER BY value of
+	// every row with the value of the current row. Nulls are sorted last
+	// and are only in range of each other.
+	numbers := make([]float64, length)
+	count := length
+	for index, key := range keys {
+		if len(key) == 0 || key[0] == nil {
+			if index < count {
+				count = index
+			}
+			continue
+		}
+		number, err := ToFloat64(key[0])
+		if err != nil {
+			return nil, INVALID_TYPE.Describe(fmt.Sprintf("RANGE frames with an offset require a numeric ORDER BY value but found %T", key[0]))
+		}
+		if window.Order[0].Direction == sqlparser.DescOrder {
+			number = -number
+		}
+		numbers[index] = number
+	}
+	bound := func(point *sqlparser.FramePoint, offset float64, index int, isStart bool) int {
+		switch point.Type {
+		case sqlparser.UnboundedPrecedingType:
+			{
+				return 0
+			}
+		case sqlparser.UnboundedFollowingType:
+			{
+				return length - 1
+			}
+		case sqlparser.CurrentRowType:
+			{
+				if isStart {
+					return peerStart[index]
+				}
+				return peerEnd[index]
+			}
+		}
+		if index >= count {
+			if isStart {
+				return peerStart[index]
+			}
+			return peerEnd[index]
+		}
+		value := numbers[index] - offset
+		if point.Type == sqlparser.ExprFollowingType {
+			value = numbers[index] + offset
+		}
+		if isStart {
+			return sort.Search(count, func(i int) bool { return numbers[i] >= value })
+		}
+		return sort.Search(count, func(i int) bool { return numbers[i] > value }) - 1
+	}
+	start, err := FrameOffset(query, frame.Start)
+	if err != nil {
+		return nil, err
+	}
+	finish, err := FrameOffset(query, end)
+	if err != nil {
+		return nil, err
+	}
+	for index := range frames {
+		frames[index] = windowFrame{
+			start: bound(frame.Start, float64(start), index, true),
+			end:   bound(end, float64(finish), index, false),
+		}
+	}
+	return frames, nil
+}
+
+// FrameRow returns the row a ROWS frame point refers to
+func FrameRow(point *sqlparser.FramePoint, offset int, index int, length int) int {
+	switch point.Type {
+	case sqlparser.UnboundedPrecedingType:
+		{
+			return 0
+		}
+	case sqlparser.UnboundedFollowingType:
+		{
+			return length - 1
+		}
+	case sqlparser.ExprPrecedingType:
+		{
+			return index - offset
+		}
+	case sqlparser.ExprFollowingType:
+		{
+			return index + offset
+		}
+	}
+	return index
+}
+
+// FrameOffset evaluates the offset of a frame point
+func FrameOffset(query *Query, point *sqlparser.FramePoint) (int, error) {
+	if point.Type != sqlparser.ExprPrecedingType && point.Type != sqlparser.ExprFollowingType {
+		return 0, nil
+	}
+	return WindowCount(query, point.Expr, 0, "window frames")
+}
+
+// WindowCount evaluates a constant argument of a window function, such as
+// the offset of LAG, that must be a non negative integer
+func WindowCount(query *Query, expr sqlparser.Expr, fallback int, name string) (int, error) {
+	if expr == nil {
+		return fallback, nil
+	}
+	value, err := WindowValue(query, Map{}, expr)
+	if err != nil {
+		return 0, err
+	}
+	number, err := ToFloat64(value)
+	if err != nil || number < 0 || number != float64(int(number)) {
+		return 0, EXPECTATION_FAILED.Describe(fmt.Sprintf("%s expects a non negative integer but found %v", name, value))
+	}
+	return int(number), nil
+}
+
+// WindowValue evaluates an expression against a row of a partition
+func WindowValue(query *Query, row Map, expr sqlparser.Expr) (any, error) {
+	rs, err := Expr(query, row, expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ValueOf(query, row, rs)
+}
+
+// AggregateOver evaluates an aggregate function over the given rows the
+// same way it is evaluated over the rows of a group
+func AggregateOver(query *Query, expr sqlparser.AggrFunc, rows []any) (any, error) {
+	function, ok := query.Registry().Function(strings.ToLower(expr.AggrName()))
+	if !ok {
+		return nil, INVALID_FUNCTION.Describe(fmt.Sprintf("function %s cannot be found", expr.AggrName()))
+	}
+	current := Map{"*": rows}
+	slice, err := AggrFuncArgReader(query, current, expr.GetArgs())
+	if err != nil {
+		return nil, err
+	}
+	return function(query, current, &FunctionOptions{Context: query.Context()}, slice)
+}
+
+// RunningAggregate computes a built-in aggregate over frames that start at
+// the first row of the partition and only grow, such as the default frame
+// or ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW, by adding the rows
+// every frame gains to the value of the previous one. It reports false
+// when the aggregate, its argument or the frames do not allow it, and the
+// aggregate has to be evaluated over every frame instead.
+func RunningAggregate(query *Query, expr sqlparser.AggrFunc, rows []Map, frames []windowFrame) ([]any, bool, error) {
+	name := strings.ToLower(expr.AggrName())
+	builtIn, ok := runningAggregates[name]
+	if !ok || expr.IsDistinct() {
+		return nil, false, nil
+	}
+	// A registry may replace the built-in function
+	function, ok := query.Registry().Function(name)
+	if !ok || reflect.ValueOf(function).Pointer() != reflect.ValueOf(builtIn).Pointer() {
+		return nil, false, nil
+	}
+	for index, frame := range frames {
+		if frame.start != 0 || (index > 0 && frame.end < frames[index-1].end) {
+			return nil, false, nil
+		}
+	}
+	column, ok := RunningColumn(query, expr)
+	if !ok {
+		return nil, false, nil
+	}
+	aggregate := &runningAggregate{name: name}
+	results := make([]any, len(frames))
+	position := 0
+	for index, frame := range frames {
+		for ; position <= frame.end; position++ {
+			var value any
+			if len(column) != 0 {
+				rs, err := query.Registry().ExecReader(rows[position], column)
+				if err != nil {
+					return nil, false, err
+				}
+				value = rs
+			}
+			err := aggregate.Add(value)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		results[index] = aggregate.Value()
+	}
+	return results, true, nil
+}
+
+// RunningColumn returns the column the argument of an aggregate reads, or
+// an empty string for COUNT(*). It reports false unless the argument is a
+// column read with plain keys, whose values over a frame are the values
+// of its rows one after the other.
+func RunningColumn(query *Query, expr sqlparser.AggrFunc) (string, bool) {
+	args := expr.GetArgs()
+	if len(args) == 0 {
+		return "", true
+	}
+	if _, ok := args[0].(*sqlparser.ColName); !ok || len(args) != 1 {
+		return "", false
+	}
+	rs, err := Expr(query, Map{"*": []any{}}, args[0], nil)
+	if err != nil {
+		return "", false
+	}
+	column, ok := rs.(ColumnName)
+	if !ok || strings.Contains(string(column), "::") {
+		return "", false
+	}
+	selectors, err := ParseSelector(string(column))
+	if err != nil || len(selectors) == 0 {
+		return "", false
+	}
+	for _, selector := range selectors {
+		key, ok := selector.(KeySelector)
+		if !ok || key == "<-" || key == "*" {
+			return "", false
+		}
+	}
+	return string(column), true
+}
+
+// Add adds the value a row reads to the aggregate. Nulls are counted as
+// rows but not as numbers, as they are by the built-in functions.
+func (aggregate *runningAggregate) Add(value any) error {
+	aggregate.rows++
+	if value == nil || aggregate.name == "count" {
+		return nil
+	}
+	number, err := ToFloat64(value)
+	if err != nil {
+		return err
+	}
+	if aggregate.numbers == 0 || number < aggregate.min {
+		aggregate.min = number
+	}
+	if aggregate.numbers == 0 || number > aggregate.max {
+		aggregate.max = number
+	}
+	aggregate.numbers++
+	aggregate.sum += number
+	return nil
+}
+
+// Value returns the value of the aggregate over the rows added so far
+func (aggregate *runningAggregate) Value() any {
+	if aggregate.name == "count" {
+		return aggregate.rows
+	}
+	if aggregate.numbers == 0 {
+		return nil
+	}
+	switch aggregate.name {
+	case "avg":
+		{
+			return aggregate.sum / float64(aggregate.rows)
+		}
+	case "min":
+		{
+			return aggregate.min
+		}
+	case "max":
+		{
+			return aggregate.max
+		}
+	}
+	return aggregate.sum
+}
+
+// WindowExpr reads the value of a window function computed for the row
+func WindowExpr(query *Query, current Map, expr sqlparser.Expr) (any, error) {
+	index, ok := query.windowLookup[expr]
+	if !ok {
+		return nil, EXPECTATION_FAILED.Describe("window functions are only allowed in SELECT and ORDER BY")
+	}
+	values, ok := current[windowKey].([]any)
+	if !ok || index >= len(values) {
+		return nil, EXPECTATION_FAILED.Describe("window functions are only allowed in SELECT and ORDER BY")
+	}
+	return values[index], nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 Pouya Vedadiyan
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWindowFunctions(t *testing.T) {
+	data := Map{
+		"items": []any{
+			Map{"id": 1.0, "category": "A", "price": 3.0},
+			Map{"id": 2.0, "category": "B", "price": 1.0},
+			Map{"id": 3.0, "category": "A", "price": 10.0},
+			Map{"id": 4.0, "category": "A", "price": 3.0},
+			Map{"id": 5.0, "category": "B", "price": 7.0},
+		},
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "Row Number",
+			query: "SELECT id, ROW_NUMBER() OVER (PARTITION BY category ORDER BY price, id) AS n FROM `root.items`",
+			want:  "[map[id:1 n:1] map[id:2 n:1] map[id:3 n:3] map[id:4 n:2] map[id:5 n:2]]",
+		},
+		{
+			name:  "Rank And Dense Rank",
+			query: "SELECT id, RANK() OVER (ORDER BY price) AS r, DENSE_RANK() OVER (ORDER BY price) AS d FROM `root.items`",
+			want:  "[map[d:2 id:1 r:2] map[d:1 id:2 r:1] map[d:4 id:3 r:5] map[d:2 id:4 r:2] map[d:3 id:5 r:4]]",
+		},
+		{
+			name:  "Ntile",
+			query: "SELECT id, NTILE(2) OVER (ORDER BY id) AS t FROM `root.items`",
+			want:  "[map[id:1 t:1] map[id:2 t:1] map[id:3 t:1] map[id:4 t:2] map[id:5 t:2]]",
+		},
+		{
+			name:  "Lag And Lead",
+			query: "SELECT id, LAG(price) OVER (ORDER BY id) AS p, LEAD(price, 2, 0) OVER (ORDER BY id) AS f FROM `root.items`",
+			want:  "[map[f:10 id:1 p:<nil>] map[f:3 id:2 p:3] map[f:7 id:3 p:1] map[f:0 id:4 p:10] map[f:0 id:5 p:3]]",
+		},
+		{
+			name:  "First And Last Value",
+			query: "SELECT id, FIRST_VALUE(id) OVER (PARTITION BY category ORDER BY price DESC) AS f, LAST_VALUE(id) OVER (PARTITION BY category ORDER BY id) AS l FROM `root.items`",
+			want:  "[map[f:3 id:1 l:1] map[f:5 id:2 l:2] map[f:3 id:3 l:3] map[f:3 id:4 l:4] map[f:5 id:5 l:5]]",
+		},
+		{
+			name:  "Running Sum",
+			query: "SELECT id, SUM(price) OVER (PARTITION BY category ORDER BY id) AS s FROM `root.items`",
+			want:  "[map[id:1 s:3] map[id:2 s:1] map[id:3 s:13] map[id:4 s:16] map[id:5 s:8]]",
+		},
+		{
+			name:  "Running Sum Includes Peers",
+			query: "SELECT id, SUM(price) OVER (ORDER BY price) AS s FROM `root.items`",
+			want:  "[map[id:1 s:7] map[id:2 s:1] map[id:3 s:24] map[id:4 s:7] map[id:5 s:14]]",
+		},
+		{
+			name:  "Rows Frame",
+			query: "SELECT id, SUM(price) OVER (ORDER BY price, id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS s FROM `root.items`",
+			want:  "[map[id:1 s:4] map[id:2 s:1] map[id:3 s:24] map[id:4 s:7] map[id:5 s:14]]",
+		},
+		{
+			name:  "Moving Average",
+			query: "SELECT id, AVG(price) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS a FROM `root.items` WHERE id < 4",
+			want:  "[map[a:2 id:1] map[a:4.666666666666667 id:2] map[a:5.5 id:3]]",
+		},
+		{
+			name:  "Range Frame",
+			query: "SELECT id, SUM(price) OVER (ORDER BY price RANGE BETWEEN 2 PRECEDING AND CURRENT ROW) AS s FROM `root.items`",
+			want:  "[map[id:1 s:7] map[id:2 s:1] map[id:3 s:10] map[id:4 s:7] map[id:5 s:7]]",
+		},
+		{
+			name:  "Descending Range Frame",
+			query: "SELECT id, COUNT(*) OVER (ORDER BY price DESC RANGE 3 PRECEDING) AS c FROM `root.items`",
+			want:  "[map[c:2 id:1] map[c:3 id:2] map[c:1 id:3] map[c:2 id:4] map[c:2 id:5]]",
+		},
+		{
+			name:  "Whole Partition",
+			query: "SELECT id, COUNT(*) OVER (PARTITION BY category) AS c, MAX(price) OVER (PARTITION BY category) AS m, MIN(price) OVER () AS n FROM `root.items`",
+			want:  "[map[c:3 id:1 m:10 n:1] map[c:2 id:2 m:7 n:1] map[c:3 id:3 m:10 n:1] map[c:3 id:4 m:10 n:1] map[c:2 id:5 m:7 n:1]]",
+		},
+		{
+			name:  "Named Window",
+			query: "SELECT id, ROW_NUMBER() OVER w AS n FROM `root.items` WINDOW w AS (ORDER BY price DESC, id)",
+			want:  "[map[id:1 n:3] map[id:2 n:5] map[id:3 n:1] map[id:4 n:4] map[id:5 n:2]]",
+		},
+		{
+			name:  "Order By Window",
+			query: "SELECT id FROM `root.items` ORDER BY ROW_NUMBER() OVER (ORDER BY price DESC, id)",
+			want:  "[map[id:3] map[id:5] map[id:1] map[id:4] map[id:2]]",
+		},
+		{
+			name:  "Over Groups",
+			query: "SELECT category, SUM(price) AS total, RANK() OVER (ORDER BY SUM(price) DESC) AS r FROM `root.items` GROUP BY category",
+			want:  "[map[category:A r:1 total:16] map[category:B r:2 total:8]]",
+		},
+		{
+			name:  "Select All",
+			query: "SELECT *, ROW_NUMBER() OVER () AS n FROM `root.items` LIMIT 1",
+			want:  "[map[category:A id:1 n:1 price:3]]",
+		},
+		{
+			name:    "Where",
+			query:   "SELECT id FROM `root.items` WHERE ROW_NUMBER() OVER () > 1",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Not An Aggregate",
+			query:   "SELECT to_lower(category) OVER () FROM `root.items`",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Undefined Window",
+			query:   "SELECT ROW_NUMBER() OVER w FROM `root.items`",
+			wantErr: EXPECTATION_FAILED,
+		},
+		{
+			name:    "Range Offset Without Order",
+			query:   "SELECT SUM(price) OVER (RANGE 1 PRECEDING) FROM `root.items`",
+			wantErr: EXPECTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(data, tt.query, Wrapped())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := q.Exec()
+			if err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			if fmt.Sprintf("%v", result) != tt.want {
+				t.Errorf("Exec() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowFunctions_DataIsNotModified(t *testing.T) {
+	items := []any{Map{"id": 1.0}, Map{"id": 2.0}}
+	plan, err := Compile("SELECT id, ROW_NUMBER() OVER (ORDER BY id DESC) AS n FROM `root`", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i := 0; i < 2; i++ {
+		rs, err := plan.Exec(items)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if out := fmt.Sprintf("%v", rs); out != "[map[id:1 n:2] map[id:2 n:1]]" {
+			t.Fatalf("unexpected result %s", out)
+		}
+	}
+	if out := fmt.Sprintf("%v", items); out != "[map[id:1] map[id:2]]" {
+		t.Fatalf("data was modified %s", out)
+	}
+}
+
+func TestWindowFunctions_RunningAggregates(t *testing.T) {
+	rows := make([]any, 0)
+	for i := 0; i < 200; i++ {
+		row := Map{"id": float64(i), "g": float64(i % 3), "k": float64(i / 4)}
+		if i%7 != 0 {
+			row["v"] = float64((i * 37) % 101)
+		}
+		rows = append(rows, row)
+	}
+	// A registry whose aggregates wrap the built-in ones computes every
+	// frame from scratch
+	registry := NewRegistry()
+	for name, function := range runningAggregates {
+		function := function
+		registry.RegisterImmediateFunction(name, func(query *Query, current Map, functionOptions *FunctionOptions, args []any) (any, error) {
+			return function(query, current, functionOptions, args)
+		})
+	}
+	windows := []string{
+		"PARTITION BY g ORDER BY k",
+		"PARTITION BY g ORDER BY k ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW",
+		"ORDER BY k RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW",
+		"ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND 2 FOLLOWING",
+		"ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING",
+	}
+	for _, window := range windows {
+		t.Run(window, func(t *testing.T) {
+			query := fmt.Sprintf("SELECT id, SUM(v) OVER w AS s, AVG(v) OVER w AS a, MIN(v) OVER w AS mn, MAX(v) OVER w AS mx, COUNT(*) OVER w AS c, COUNT(v) OVER w AS cv FROM `root.rows` WINDOW w AS (%s) ORDER BY id", window)
+			results := make([]string, 0)
+			for _, registry := range []*Registry{DefaultRegistry(), registry} {
+				q, err := New(Map{"rows": rows}, query, Wrapped(), WithRegistry(registry))
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				rs, err := q.Exec()
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				results = append(results, fmt.Sprintf("%v", rs))
+			}
+			if results[0] != results[1] {
+				t.Fatalf("expected %s but found %s", results[1], results[0])
+			}
+		})
+	}
+}
+
+func TestWindowFunctions_LargePartition(t *testing.T) {
+	rows := make([]any, 0)
+	for i := 0; i < 50000; i++ {
+		rows = append(rows, Map{"id": float64(i), "v": 1.0})
+	}
+	query, err := New(Map{"rows": rows}, "SELECT id, SUM(v) OVER (ORDER BY id) AS s, COUNT(*) OVER (ORDER BY id ROWS UNBOUNDED PRECEDING) AS c FROM `root.rows`", Wrapped())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs, err := query.Exec()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(rs) != 50000 {
+		t.Fatalf("expected 50000 rows but found %d", len(rs))
+	}
+	last := rs[len(rs)-1].(Map)
+	if last["s"] != 50000.0 || last["c"] != 50000 {
+		t.Fatalf("unexpected last row %v", last)
+	}
+}